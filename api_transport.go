@@ -0,0 +1,97 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	metrics "github.com/hashicorp/go-metrics/compat"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+type logFieldsKey struct{}
+
+// withLogFields returns a context carrying additional key/value pairs that
+// are added to the logs of the Terraform API requests made with it. Values
+// must never be secrets.
+func withLogFields(ctx context.Context, args ...interface{}) context.Context {
+	fields := append(append([]interface{}{}, logFields(ctx)...), args...)
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// withRoleLogFields returns a context carrying the Vault request ID, if any,
+// and the name and credential type of the role, to correlate the Terraform API
+// requests made for a role.
+func withRoleLogFields(ctx context.Context, req *logical.Request, roleEntry *terraformRoleEntry) context.Context {
+	var args []interface{}
+	if req != nil && req.ID != "" {
+		args = append(args, "request_id", req.ID)
+	}
+	return withLogFields(ctx, append(args,
+		"role", roleEntry.Name,
+		"credential_type", roleEntry.CredentialType,
+	)...)
+}
+
+func logFields(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(logFieldsKey{}).([]interface{})
+	return fields
+}
+
+// apiTransport logs and measures the requests made to the Terraform API: their
+// endpoint, status code and duration, along with the log fields of the request
+// context. Request and response bodies and headers are never logged, as they
+// may hold tokens.
+type apiTransport struct {
+	base     http.RoundTripper
+	basePath string
+	logger   hclog.Logger
+}
+
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := apiEndpoint(t.basePath, req.URL.Path)
+	labels := []metrics.Label{
+		{Name: "endpoint", Value: endpoint},
+		{Name: "method", Value: req.Method},
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	duration := time.Since(start)
+	metrics.MeasureSinceWithLabels(metricName("api", "request"), start, labels)
+
+	args := append([]interface{}{
+		"method", req.Method,
+		"endpoint", endpoint,
+		"path", req.URL.Path,
+		"duration", duration,
+	}, logFields(req.Context())...)
+
+	if err != nil {
+		metrics.IncrCounterWithLabels(metricName("api", "response"), 1, append(labels, metrics.Label{Name: "status_code", Value: "error"}))
+		t.logger.Error("terraform api request failed", append(args, "error", err)...)
+		return resp, err
+	}
+
+	metrics.IncrCounterWithLabels(metricName("api", "response"), 1, append(labels, metrics.Label{Name: "status_code", Value: strconv.Itoa(resp.StatusCode)}))
+	args = append(args, "status", resp.StatusCode)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		metrics.IncrCounterWithLabels(metricName("api", "throttled"), 1, labels)
+		t.logger.Warn("terraform api request throttled", args...)
+	case resp.StatusCode >= http.StatusInternalServerError,
+		resp.StatusCode == http.StatusUnauthorized,
+		resp.StatusCode == http.StatusForbidden:
+		t.logger.Warn("terraform api request", args...)
+	default:
+		t.logger.Debug("terraform api request", args...)
+	}
+
+	return resp, err
+}
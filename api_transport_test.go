@@ -0,0 +1,82 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITransportLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/authentication-tokens"):
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := hclog.New(&hclog.LoggerOptions{
+		Output:     &buf,
+		Level:      hclog.Trace,
+		JSONFormat: true,
+	})
+
+	client := &http.Client{Transport: &apiTransport{
+		base:     http.DefaultTransport,
+		basePath: "/api/v2/",
+		logger:   logger,
+	}}
+
+	ctx := withRoleLogFields(context.Background(), &logical.Request{ID: "req-123"}, &terraformRoleEntry{
+		Name:           "ci",
+		CredentialType: teamCredentialType,
+	})
+	ctx = withLogFields(ctx, "token_id", "at-123")
+
+	for _, path := range []string{"/api/v2/ping", "/api/v2/teams/team-123/authentication-tokens"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret-token")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	require.NotContains(t, buf.String(), "secret-token")
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "debug", entries[0]["@level"])
+	require.Equal(t, "ping", entries[0]["endpoint"])
+	require.Equal(t, float64(http.StatusNoContent), entries[0]["status"])
+	require.Equal(t, "req-123", entries[0]["request_id"])
+	require.Equal(t, "ci", entries[0]["role"])
+	require.Equal(t, teamCredentialType, entries[0]["credential_type"])
+	require.Equal(t, "at-123", entries[0]["token_id"])
+	require.Contains(t, entries[0], "duration")
+
+	require.Equal(t, "warn", entries[1]["@level"])
+	require.Equal(t, "terraform api request throttled", entries[1]["@message"])
+	require.Equal(t, "teams/*/authentication-tokens", entries[1]["endpoint"])
+}
@@ -7,8 +7,11 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/salt"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -28,10 +31,31 @@ type tfBackend struct {
 	*framework.Backend
 	lock   sync.RWMutex
 	client *client
+
+	// usageLocks serialize updates to the issuance counts of roles
+	usageLocks []*locksutil.LockEntry
+
+	// roleLocks serialize writes and token rotations of roles
+	roleLocks []*locksutil.LockEntry
+
+	// lastErrors keeps the last failure of each operation for the status
+	// endpoint
+	lastErrors lastErrors
+
+	// issuedTokensScannedAt is when the issued token records were last
+	// scanned by the periodic function
+	issuedTokensScanLock  sync.Mutex
+	issuedTokensScannedAt time.Time
+
+	saltLock sync.RWMutex
+	salt     *salt.Salt
 }
 
 func backend() *tfBackend {
-	b := tfBackend{}
+	b := tfBackend{
+		usageLocks: locksutil.CreateLocks(),
+		roleLocks:  locksutil.CreateLocks(),
+	}
 
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(backendHelp),
@@ -51,12 +75,26 @@ func backend() *tfBackend {
 				pathCredentials(&b),
 			},
 			pathRotateRole(&b),
+			pathStaticRole(&b),
+			pathUpgradeRole(&b),
+			[]*framework.Path{
+				pathStaticCredentials(&b),
+				pathExport(&b),
+				pathImport(&b),
+				pathStatus(&b),
+				pathLookup(&b),
+				pathRevokeToken(&b),
+			},
 		),
 		Secrets: []*framework.Secret{
 			b.terraformToken(),
 		},
-		BackendType: logical.TypeLogical,
-		Invalidate:  b.invalidate,
+		BackendType:       logical.TypeLogical,
+		InitializeFunc:    b.initialize,
+		Invalidate:        b.invalidate,
+		WALRollback:       b.walRollback,
+		PeriodicFunc:      b.periodicFunc,
+		WALRollbackMinAge: walRollbackMinAge,
 	}
 
 	return &b
@@ -69,8 +107,11 @@ func (b *tfBackend) reset() {
 }
 
 func (b *tfBackend) invalidate(ctx context.Context, key string) {
-	if key == "config" {
+	switch key {
+	case "config":
 		b.reset()
+	case salt.DefaultLocation:
+		b.resetSalt()
 	}
 }
 
@@ -98,7 +139,7 @@ func (b *tfBackend) getClient(ctx context.Context, s logical.Storage) (*client,
 		}
 	}
 
-	b.client, err = newClient(config)
+	b.client, err = newClient(config, b.Logger())
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,8 @@ package tfc
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -38,6 +40,36 @@ func getTestBackend(tb testing.TB) (*tfBackend, logical.Storage) {
 	return b.(*tfBackend), config.StorageView
 }
 
+// newTestServer starts a fake Terraform Cloud / Enterprise API and configures
+// the backend to use it. Routes are http.ServeMux patterns, such as
+// "POST /api/v2/organizations/acme/authentication-token", and are answered
+// with the JSON:API content type. Unknown paths return 404.
+func newTestServer(t *testing.T, b *tfBackend, s logical.Storage, routes map[string]http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	for pattern, handler := range routes {
+		mux.HandleFunc(pattern, handler)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	err := testConfigCreate(t, b, s, map[string]interface{}{
+		"token":   "test-token",
+		"address": server.URL,
+	})
+	require.NoError(t, err)
+
+	return server
+}
+
 var runAcceptanceTests = os.Getenv(envVarRunAccTests) == "1"
 
 type testEnv struct {
@@ -89,7 +121,7 @@ func (e *testEnv) AddOrgTokenRole(t *testing.T) {
 func (e *testEnv) ReadOrgToken(t *testing.T) {
 	req := &logical.Request{
 		Operation: logical.ReadOperation,
-		Path:      "creds/test-org-token",
+		Path:      "static-creds/test-org-token",
 		Storage:   e.Storage,
 	}
 	resp, err := e.Backend.HandleRequest(e.Context, req)
@@ -131,7 +163,7 @@ func (e *testEnv) AddTeamLegacyTokenRole(t *testing.T) {
 func (e *testEnv) ReadTeamLegacyToken(t *testing.T) {
 	req := &logical.Request{
 		Operation: logical.ReadOperation,
-		Path:      "creds/test-team-token",
+		Path:      "static-creds/test-team-token",
 		Storage:   e.Storage,
 	}
 	resp, err := e.Backend.HandleRequest(e.Context, req)
@@ -7,11 +7,16 @@ import (
 	"errors"
 	"time"
 
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-tfe"
 )
 
 type client struct {
 	*tfe.Client
+
+	// logger receives the logs of the API requests made by the client
+	logger hclog.Logger
 }
 
 type terraformToken struct {
@@ -21,15 +26,23 @@ type terraformToken struct {
 	ExpiredAt   time.Time `json:"expired_at,omitempty"`
 }
 
-func newClient(config *tfConfig) (*client, error) {
+func newClient(config *tfConfig, logger hclog.Logger) (*client, error) {
 	if config == nil {
 		return nil, errors.New("client configuration was nil")
 	}
 
+	httpClient := cleanhttp.DefaultPooledClient()
+	httpClient.Transport = &apiTransport{
+		base:     httpClient.Transport,
+		basePath: config.BasePath,
+		logger:   logger,
+	}
+
 	cfg := &tfe.Config{
-		Address:  config.Address,
-		BasePath: config.BasePath,
-		Token:    config.Token,
+		Address:    config.Address,
+		BasePath:   config.BasePath,
+		Token:      config.Token,
+		HTTPClient: httpClient,
 	}
 
 	tfc, err := tfe.NewClient(cfg)
@@ -38,6 +51,7 @@ func newClient(config *tfConfig) (*client, error) {
 	}
 
 	return &client{
-		tfc,
+		Client: tfc,
+		logger: logger,
 	}, nil
 }
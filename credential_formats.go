@@ -0,0 +1,87 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	credsFormatDefault = "default"
+	credsFormatTFRC    = "tfrc"
+	credsFormatEnv     = "env"
+	credsFormatAll     = "all"
+)
+
+func credsFormat_Values() []string {
+	return []string{
+		credsFormatDefault,
+		credsFormatTFRC,
+		credsFormatEnv,
+		credsFormatAll,
+	}
+}
+
+// credentialsHost returns the host Terraform CLI uses to look up credentials
+// for the configured address, e.g. "app.terraform.io".
+func credentialsHost(address string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("error parsing address %q: %w", address, err)
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("address %q has no host", address)
+	}
+
+	host, err := idna.Lookup.ToASCII(u.Hostname())
+	if err != nil {
+		return "", fmt.Errorf("error encoding host of address %q: %w", address, err)
+	}
+
+	if port := u.Port(); port != "" {
+		host = host + ":" + port
+	}
+
+	return host, nil
+}
+
+// credentialsTFRCJSON returns a credentials.tfrc.json document holding the
+// token for the host.
+func credentialsTFRCJSON(host string, token string) (string, error) {
+	doc := map[string]interface{}{
+		"credentials": map[string]interface{}{
+			host: map[string]string{
+				"token": token,
+			},
+		},
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// tokenEnvVarName returns the TF_TOKEN_ environment variable Terraform CLI
+// reads the token for the host from. Periods are encoded as underscores and
+// hyphens as double underscores. Hosts with a port cannot be expressed as an
+// environment variable.
+func tokenEnvVarName(host string) (string, error) {
+	if strings.Contains(host, ":") {
+		return "", fmt.Errorf("host %q includes a port, which TF_TOKEN_ variables cannot express", host)
+	}
+
+	name := strings.ReplaceAll(host, "-", "__")
+	name = strings.ReplaceAll(name, ".", "_")
+
+	return "TF_TOKEN_" + name, nil
+}
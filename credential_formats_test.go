@@ -0,0 +1,62 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialsHost(t *testing.T) {
+	cases := map[string]struct {
+		address     string
+		host        string
+		envVarName  string
+		expectedErr string
+	}{
+		"terraform cloud": {
+			address:    "https://app.terraform.io",
+			host:       "app.terraform.io",
+			envVarName: "TF_TOKEN_app_terraform_io",
+		},
+		"hyphenated host": {
+			address:    "https://tfe-prod.example.com/",
+			host:       "tfe-prod.example.com",
+			envVarName: "TF_TOKEN_tfe__prod_example_com",
+		},
+		"internationalized host": {
+			address:    "https://café.fr",
+			host:       "xn--caf-dma.fr",
+			envVarName: "TF_TOKEN_xn____caf__dma_fr",
+		},
+		"host with port": {
+			address:     "https://tfe.example.com:8443",
+			host:        "tfe.example.com:8443",
+			expectedErr: "includes a port",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			host, err := credentialsHost(tc.address)
+			require.NoError(t, err)
+			require.Equal(t, tc.host, host)
+
+			envVarName, err := tokenEnvVarName(host)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.envVarName, envVarName)
+		})
+	}
+}
+
+func TestCredentialsTFRCJSON(t *testing.T) {
+	doc, err := credentialsTFRCJSON("app.terraform.io", "secret")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"credentials":{"app.terraform.io":{"token":"secret"}}}`, doc)
+}
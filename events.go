@@ -0,0 +1,47 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	eventTypeConfigWrite  = "terraform/config-write"
+	eventTypeConfigDelete = "terraform/config-delete"
+	eventTypeCredsIssue   = "terraform/creds-issue"
+	eventTypeCredsRevoke  = "terraform/creds-revoke"
+	eventTypeRoleRotate   = "terraform/role-rotate"
+	eventTypeTokenRevoke  = "terraform/token-revoke"
+)
+
+// sendEvent sends a Vault event with the given metadata. Events never carry
+// tokens, and failing to send one does not fail the request.
+func (b *tfBackend) sendEvent(ctx context.Context, eventType string, metadataPairs ...string) {
+	err := logical.SendEvent(ctx, b, eventType, metadataPairs...)
+	if err != nil && !errors.Is(err, framework.ErrNoEvents) {
+		b.Logger().Error("unable to send event", "event_type", eventType, "error", err)
+	}
+}
+
+// tokenEventMetadata returns the event metadata describing a token issued,
+// rotated or revoked for a role.
+func tokenEventMetadata(roleName string, credentialType string, tokenID string, expiredAt time.Time) []string {
+	metadata := []string{
+		"modified", "true",
+		"data_path", "role/" + roleName,
+		"role", roleName,
+		"credential_type", credentialType,
+		"token_id", tokenID,
+	}
+	if !expiredAt.IsZero() {
+		metadata = append(metadata, "expired_at", expiredAt.Format(time.RFC3339))
+	}
+	return metadata
+}
@@ -0,0 +1,129 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	Type     logical.EventType
+	Metadata map[string]interface{}
+}
+
+type testEventSender struct {
+	lock   sync.Mutex
+	events []testEvent
+}
+
+func (s *testEventSender) SendEvent(_ context.Context, eventType logical.EventType, event *logical.EventData) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, testEvent{Type: eventType, Metadata: event.Metadata.AsMap()})
+	return nil
+}
+
+func (s *testEventSender) pop() []testEvent {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	events := s.events
+	s.events = nil
+	return events
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	events := new(testEventSender)
+
+	config := logical.TestBackendConfig()
+	config.StorageView = new(logical.InmemStorage)
+	config.Logger = hclog.NewNullLogger()
+	config.System = logical.TestSystemView()
+	config.EventsSender = events
+
+	lb, err := Factory(ctx, config)
+	require.NoError(t, err)
+	b, s := lb.(*tfBackend), config.StorageView
+
+	expiredAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	server := newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"POST /api/v2/users/user-123/authentication-tokens": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"data":{"id":"at-new","type":"authentication-tokens","attributes":{"token":"secret-token","expired-at":%q}}}`, expiredAt.Format(time.RFC3339))
+		},
+		"DELETE /api/v2/authentication-tokens/at-new": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	t.Run("config", func(t *testing.T) {
+		// the test server wrote the config
+		sent := events.pop()
+		require.Len(t, sent, 1)
+		require.Equal(t, logical.EventType(eventTypeConfigWrite), sent[0].Type)
+		require.Equal(t, server.URL, sent[0].Metadata["address"])
+		require.NotContains(t, sent[0].Metadata, "token")
+	})
+
+	t.Run("creds issue and revoke", func(t *testing.T) {
+		require.NoError(t, setRole(ctx, s, roleName, &terraformRoleEntry{
+			Name:           roleName,
+			UserID:         "user-123",
+			CredentialType: userCredentialType,
+		}))
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + roleName,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		sent := events.pop()
+		require.Len(t, sent, 1)
+		require.Equal(t, logical.EventType(eventTypeCredsIssue), sent[0].Type)
+		require.Equal(t, map[string]interface{}{
+			"modified":        "true",
+			"data_path":       "role/" + roleName,
+			"role":            roleName,
+			"credential_type": userCredentialType,
+			"token_id":        "at-new",
+			"expired_at":      expiredAt.Format(time.RFC3339),
+		}, sent[0].Metadata)
+
+		_, err = b.terraformTokenRevoke(ctx, &logical.Request{
+			Storage: s,
+			Secret:  resp.Secret,
+		}, nil)
+		require.NoError(t, err)
+
+		sent = events.pop()
+		require.Len(t, sent, 1)
+		require.Equal(t, logical.EventType(eventTypeCredsRevoke), sent[0].Type)
+		require.Equal(t, "at-new", sent[0].Metadata["token_id"])
+	})
+
+	t.Run("config delete", func(t *testing.T) {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "config",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+
+		sent := events.pop()
+		require.Len(t, sent, 1)
+		require.Equal(t, logical.EventType(eventTypeConfigDelete), sent[0].Type)
+	})
+}
@@ -0,0 +1,280 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	issuedTokenPrefix     = "issued-token/"
+	issuedTokenHashPrefix = "issued-token-hash/"
+
+	// issuedTokenRetention is how long the record of a token is kept after it
+	// was revoked or expired, so leaked tokens can still be traced.
+	issuedTokenRetention = 30 * 24 * time.Hour
+
+	// issuedTokenScanInterval is how often the issued token records are
+	// scanned. Records are kept for issuedTokenRetention, so a busy mount
+	// has too many to read on every run of the periodic function.
+	issuedTokenScanInterval = time.Hour
+)
+
+// issuedToken records a user or team token issued by the mount. It never
+// holds the token itself; tokens are found by their salted hash instead.
+type issuedToken struct {
+	TokenID        string    `json:"token_id"`
+	Role           string    `json:"role"`
+	CredentialType string    `json:"credential_type"`
+	LeaseID        string    `json:"lease_id,omitempty"`
+	EntityID       string    `json:"entity_id,omitempty"`
+	RequestID      string    `json:"request_id,omitempty"`
+	IssuedAt       time.Time `json:"issued_at"`
+	ExpiredAt      time.Time `json:"expired_at,omitempty"`
+	// LeaseExpiresAt is the max TTL of the lease, past which the lease is
+	// gone even if its revocation never reached the mount
+	LeaseExpiresAt time.Time `json:"lease_expires_at,omitempty"`
+	RevokedAt      time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy     string    `json:"replaced_by,omitempty"`
+	// BreakGlass is set when the token was revoked through revoke-token
+	// rather than by revoking its lease
+	BreakGlass bool `json:"break_glass,omitempty"`
+}
+
+func (t *issuedToken) toResponseData() map[string]interface{} {
+	data := map[string]interface{}{
+		"token_id":        t.TokenID,
+		"role":            t.Role,
+		"credential_type": t.CredentialType,
+		"lease_id":        t.LeaseID,
+		"entity_id":       t.EntityID,
+		"request_id":      t.RequestID,
+		"issued_at":       t.IssuedAt.Format(time.RFC3339),
+	}
+	if !t.ExpiredAt.IsZero() {
+		data["expired_at"] = t.ExpiredAt.Format(time.RFC3339)
+	}
+	if !t.RevokedAt.IsZero() {
+		data["revoked_at"] = t.RevokedAt.Format(time.RFC3339)
+	}
+	if t.ReplacedBy != "" {
+		data["replaced_by_token_id"] = t.ReplacedBy
+	}
+	if t.BreakGlass {
+		data["break_glass"] = true
+	}
+	return data
+}
+
+// isActive reports whether the token still counts against the
+// max_active_tokens of its role: it was neither revoked nor replaced, and
+// neither the token nor its lease has expired.
+func (t *issuedToken) isActive(now time.Time) bool {
+	switch {
+	case !t.RevokedAt.IsZero() || t.ReplacedBy != "":
+		return false
+	case !t.ExpiredAt.IsZero() && !now.Before(t.ExpiredAt):
+		return false
+	case !t.LeaseExpiresAt.IsZero() && !now.Before(t.LeaseExpiresAt):
+		return false
+	default:
+		return true
+	}
+}
+
+// retainUntil returns when the record can be removed, or the zero time if the
+// token is still live.
+func (t *issuedToken) retainUntil() time.Time {
+	switch {
+	case !t.RevokedAt.IsZero():
+		return t.RevokedAt.Add(issuedTokenRetention)
+	case !t.ExpiredAt.IsZero():
+		return t.ExpiredAt.Add(issuedTokenRetention)
+	case !t.LeaseExpiresAt.IsZero():
+		return t.LeaseExpiresAt.Add(issuedTokenRetention)
+	default:
+		return time.Time{}
+	}
+}
+
+func getIssuedToken(ctx context.Context, s logical.Storage, tokenID string) (*issuedToken, error) {
+	entry, err := s.Get(ctx, issuedTokenPrefix+tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	record := new(issuedToken)
+	if err := entry.DecodeJSON(record); err != nil {
+		return nil, fmt.Errorf("error reading issued token %q: %w", tokenID, err)
+	}
+	return record, nil
+}
+
+func putIssuedToken(ctx context.Context, s logical.Storage, record *issuedToken) error {
+	entry, err := logical.StorageEntryJSON(issuedTokenPrefix+record.TokenID, record)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+// recordIssuedToken stores the record of a newly issued token, along with an
+// index from the salted hash of the token to its ID.
+func (b *tfBackend) recordIssuedToken(ctx context.Context, s logical.Storage, record *issuedToken, token string) error {
+	hash, err := b.hashToken(ctx, s, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key:   issuedTokenHashPrefix + hash,
+		Value: []byte(record.TokenID),
+	}); err != nil {
+		return err
+	}
+
+	return putIssuedToken(ctx, s, record)
+}
+
+// issuedTokenIDForToken returns the ID of a token issued by the mount, or an
+// empty string if the mount has no record of it.
+func (b *tfBackend) issuedTokenIDForToken(ctx context.Context, s logical.Storage, token string) (string, error) {
+	hash, err := b.hashToken(ctx, s, token)
+	if err != nil {
+		return "", err
+	}
+
+	entry, err := s.Get(ctx, issuedTokenHashPrefix+hash)
+	if err != nil || entry == nil {
+		return "", err
+	}
+	return string(entry.Value), nil
+}
+
+// leaseExpiresAt returns when a lease issued at issueTime with maxTTL is gone
+// at the latest, or the zero time if that is unknown. Vault caps the max TTL
+// of a lease at the max of the mount.
+func (b *tfBackend) leaseExpiresAt(issueTime time.Time, maxTTL time.Duration) time.Time {
+	if mountMax := b.System().MaxLeaseTTL(); maxTTL == 0 || (mountMax > 0 && mountMax < maxTTL) {
+		maxTTL = mountMax
+	}
+
+	if issueTime.IsZero() || maxTTL <= 0 {
+		return time.Time{}
+	}
+	return issueTime.Add(maxTTL)
+}
+
+// updateIssuedToken applies update to the record of a token, if there is one.
+// Tokens issued before records were kept have none.
+func updateIssuedToken(ctx context.Context, s logical.Storage, tokenID string, update func(*issuedToken)) error {
+	if tokenID == "" {
+		return nil
+	}
+
+	record, err := getIssuedToken(ctx, s, tokenID)
+	if err != nil || record == nil {
+		return err
+	}
+
+	update(record)
+	return putIssuedToken(ctx, s, record)
+}
+
+// scanIssuedTokensIfDue scans the issued token records if they were not
+// scanned within issuedTokenScanInterval.
+func (b *tfBackend) scanIssuedTokensIfDue(ctx context.Context, s logical.Storage, now time.Time) error {
+	b.issuedTokensScanLock.Lock()
+	defer b.issuedTokensScanLock.Unlock()
+
+	if !b.issuedTokensScannedAt.IsZero() && now.Sub(b.issuedTokensScannedAt) < issuedTokenScanInterval {
+		return nil
+	}
+
+	if err := b.scanIssuedTokens(ctx, s, now); err != nil {
+		return err
+	}
+
+	b.issuedTokensScannedAt = now
+	return nil
+}
+
+// scanIssuedTokens makes a single pass over the records of issued tokens. It
+// removes the records of tokens that were revoked or expired longer than
+// issuedTokenRetention ago, along with the hash index entries pointing at
+// them, and recounts the active tokens of each role from the others.
+func (b *tfBackend) scanIssuedTokens(ctx context.Context, s logical.Storage, now time.Time) error {
+	tokenIDs, err := s.List(ctx, issuedTokenPrefix)
+	if err != nil {
+		return err
+	}
+
+	active := make(map[string]int)
+	removed := make(map[string]bool)
+	for _, tokenID := range tokenIDs {
+		record, err := getIssuedToken(ctx, s, tokenID)
+		if err != nil {
+			b.Logger().Error("unable to read issued token record", "token_id", tokenID, "error", err)
+			continue
+		}
+
+		if record == nil {
+			continue
+		}
+
+		if retainUntil := record.retainUntil(); retainUntil.IsZero() || now.Before(retainUntil) {
+			if record.isActive(now) {
+				active[record.Role]++
+			}
+			continue
+		}
+
+		if err := s.Delete(ctx, issuedTokenPrefix+tokenID); err != nil {
+			return err
+		}
+		removed[tokenID] = true
+	}
+
+	if err := pruneIssuedTokenHashes(ctx, s, removed); err != nil {
+		return err
+	}
+
+	return b.reconcileRoleUsage(ctx, s, active, now)
+}
+
+// pruneIssuedTokenHashes removes the hash index entries pointing at the
+// removed token records.
+func pruneIssuedTokenHashes(ctx context.Context, s logical.Storage, removed map[string]bool) error {
+	if len(removed) == 0 {
+		return nil
+	}
+
+	hashes, err := s.List(ctx, issuedTokenHashPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		entry, err := s.Get(ctx, issuedTokenHashPrefix+hash)
+		if err != nil {
+			return err
+		}
+		if entry != nil && removed[string(entry.Value)] {
+			if err := s.Delete(ctx, issuedTokenHashPrefix+hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
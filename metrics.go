@@ -0,0 +1,102 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"strings"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+)
+
+const (
+	metricsStatusSucceeded = "succeeded"
+	metricsStatusFailed    = "failed"
+
+	rotationTriggerManual     = "manual"
+	rotationTriggerScheduled  = "scheduled"
+	rotationTriggerBreakGlass = "break_glass"
+)
+
+// metricsPrefix is prepended to the name of all metrics of the backend.
+var metricsPrefix = []string{"secrets", "terraform"}
+
+func metricName(name ...string) []string {
+	return append(append([]string{}, metricsPrefix...), name...)
+}
+
+func metricsStatus(err error) string {
+	if err != nil {
+		return metricsStatusFailed
+	}
+	return metricsStatusSucceeded
+}
+
+// emitCredsMetric counts a user or team token issued for a role, or a failure
+// to issue one.
+func emitCredsMetric(roleEntry *terraformRoleEntry, err error) {
+	metrics.IncrCounterWithLabels(metricName("creds", "issue"), 1, []metrics.Label{
+		{Name: "credential_type", Value: roleEntry.CredentialType},
+		{Name: "role", Value: roleEntry.Name},
+		{Name: "status", Value: metricsStatus(err)},
+	})
+}
+
+// emitRevocationMetric counts a lease revocation.
+func emitRevocationMetric(credentialType string, role string, err error) {
+	metrics.IncrCounterWithLabels(metricName("revoke"), 1, []metrics.Label{
+		{Name: "credential_type", Value: credentialType},
+		{Name: "role", Value: role},
+		{Name: "status", Value: metricsStatus(err)},
+	})
+}
+
+// emitRotationMetric counts a manual or scheduled rotation of a role token.
+func emitRotationMetric(roleEntry *terraformRoleEntry, trigger string, err error) {
+	metrics.IncrCounterWithLabels(metricName("rotate"), 1, []metrics.Label{
+		{Name: "credential_type", Value: roleEntry.CredentialType},
+		{Name: "role", Value: roleEntry.Name},
+		{Name: "trigger", Value: trigger},
+		{Name: "status", Value: metricsStatus(err)},
+	})
+}
+
+// apiPathSegments are the fixed segments of the Terraform API paths used by
+// the backend. Any other segment is an ID or name, and is replaced to keep the
+// cardinality of the endpoint label low.
+var apiPathSegments = map[string]bool{
+	"account":                  true,
+	"admin":                    true,
+	"authentication-token":     true,
+	"authentication-tokens":    true,
+	"details":                  true,
+	"organization-memberships": true,
+	"organizations":            true,
+	"ping":                     true,
+	"relationships":            true,
+	"team-memberships":         true,
+	"teams":                    true,
+	"users":                    true,
+	"vars":                     true,
+	"varsets":                  true,
+	"workspaces":               true,
+}
+
+// apiEndpoint returns the path of an API request relative to the base path,
+// with IDs and names replaced by "*".
+func apiEndpoint(basePath string, path string) string {
+	path = strings.TrimPrefix(path, "/"+strings.Trim(basePath, "/"))
+
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if !apiPathSegments[segment] {
+			segment = "*"
+		}
+		segments = append(segments, segment)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,78 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	metrics "github.com/hashicorp/go-metrics/compat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"/api/v2/teams/team-123/authentication-tokens":     "teams/*/authentication-tokens",
+		"/api/v2/organizations/acme/authentication-token":  "organizations/*/authentication-token",
+		"/api/v2/authentication-tokens/at-123":             "authentication-tokens/*",
+		"/api/v2/account/details":                          "account/details",
+		"/api/v2/varsets/varset-123/relationships/vars/v1": "varsets/*/relationships/vars/*",
+		"/api/v2/ping": "ping",
+	}
+
+	for path, expected := range cases {
+		require.Equal(t, expected, apiEndpoint("/api/v2/", path), path)
+	}
+}
+
+func TestMetricsTransport(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	cfg := metrics.DefaultConfig("vault")
+	cfg.EnableHostname = false
+	cfg.EnableRuntimeMetrics = false
+	_, err := metrics.NewGlobal(cfg, sink)
+	require.NoError(t, err)
+	defer metrics.NewGlobal(cfg, &metrics.BlackholeSink{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/authentication-tokens") {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &apiTransport{
+		base:     http.DefaultTransport,
+		basePath: "/api/v2/",
+		logger:   hclog.NewNullLogger(),
+	}}
+
+	for _, path := range []string{"/api/v2/ping", "/api/v2/teams/team-123/authentication-tokens"} {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	var counters []string
+	var samples []string
+	for _, interval := range sink.Data() {
+		for key := range interval.Counters {
+			counters = append(counters, key)
+		}
+		for key := range interval.Samples {
+			samples = append(samples, key)
+		}
+	}
+
+	require.Contains(t, counters, "vault.secrets.terraform.api.response;endpoint=ping;method=GET;status_code=204")
+	require.Contains(t, counters, "vault.secrets.terraform.api.response;endpoint=teams/*/authentication-tokens;method=GET;status_code=429")
+	require.Contains(t, counters, "vault.secrets.terraform.api.throttled;endpoint=teams/*/authentication-tokens;method=GET")
+	require.Contains(t, samples, "vault.secrets.terraform.api.request;endpoint=ping;method=GET")
+}
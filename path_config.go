@@ -16,10 +16,16 @@ const (
 	configStoragePath = "config"
 )
 
+const (
+	defaultAddress  = "https://app.terraform.io"
+	defaultBasePath = "/api/v2/"
+)
+
 type tfConfig struct {
 	Token    string `json:"token"`
 	Address  string `json:"address"`
 	BasePath string `json:"base_path"`
+	TokenID  string `json:"token_id,omitempty"`
 }
 
 func pathConfig(b *tfBackend) *framework.Path {
@@ -38,17 +44,21 @@ func pathConfig(b *tfBackend) *framework.Path {
 					Sensitive: true,
 				},
 			},
+			"token_id": {
+				Type:        framework.TypeString,
+				Description: "The ID of the token, used to report its expiry on the status endpoint.",
+			},
 			"address": {
 				Type: framework.TypeString,
 				Description: `The address to access Terraform Cloud or Enterprise.
 				Default is "https://app.terraform.io".`,
-				Default: "https://app.terraform.io",
+				Default: defaultAddress,
 			},
 			"base_path": {
 				Type: framework.TypeString,
 				Description: `The base path for the Terraform Cloud or Enterprise API.
 				Default is "/api/v2/".`,
-				Default: "/api/v2/",
+				Default: defaultBasePath,
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -98,12 +108,18 @@ func (b *tfBackend) pathConfigRead(ctx context.Context, req *logical.Request, da
 		return nil, err
 	}
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"address":   config.Address,
 			"base_path": config.BasePath,
 		},
-	}, nil
+	}
+
+	if config.TokenID != "" {
+		resp.Data["token_id"] = config.TokenID
+	}
+
+	return resp, nil
 }
 
 func (b *tfBackend) pathConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -130,6 +146,10 @@ func (b *tfBackend) pathConfigWrite(ctx context.Context, req *logical.Request, d
 		config.Token = token.(string)
 	}
 
+	if tokenID, ok := data.GetOk("token_id"); ok {
+		config.TokenID = tokenID.(string)
+	}
+
 	entry, err := logical.StorageEntryJSON(configStoragePath, config)
 	if err != nil {
 		return nil, err
@@ -142,6 +162,14 @@ func (b *tfBackend) pathConfigWrite(ctx context.Context, req *logical.Request, d
 	// reset the client so the next invocation will pick up the new configuration
 	b.reset()
 
+	b.sendEvent(ctx, eventTypeConfigWrite,
+		"modified", "true",
+		"operation", string(req.Operation),
+		"data_path", configStoragePath,
+		"address", config.Address,
+		"base_path", config.BasePath,
+	)
+
 	return nil, nil
 }
 
@@ -150,6 +178,11 @@ func (b *tfBackend) pathConfigDelete(ctx context.Context, req *logical.Request,
 
 	if err == nil {
 		b.reset()
+		b.sendEvent(ctx, eventTypeConfigDelete,
+			"modified", "true",
+			"operation", string(req.Operation),
+			"data_path", configStoragePath,
+		)
 	}
 
 	return nil, err
@@ -186,4 +219,7 @@ to allow Vault to create tokens.
 
 If you are running Terraform Enterprise, you can specify the address and base path
 for your instance and API endpoint.
+
+Optionally set token_id to the ID of the token, so the "status" endpoint can
+report when it expires.
 `
@@ -7,7 +7,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/go-tfe"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -25,6 +29,23 @@ func pathCredentials(b *tfBackend) *framework.Path {
 				Description: "Name of the role",
 				Required:    true,
 			},
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Lease for the generated credentials, also used as the expiry of the token when max_ttl is not set. Cannot exceed the max_ttl of the role. If not set, the ttl of the role is used.",
+			},
+			"max_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lifetime of the generated credentials, also used as the expiry of the token. Cannot exceed the max_ttl of the role. If not set, the max_ttl of the role is used.",
+			},
+			"description_suffix": {
+				Type:        framework.TypeString,
+				Description: "Text appended to the description of the generated token.",
+			},
+			"format": {
+				Type:        framework.TypeString,
+				Description: "Additional output formats for the token. Can be 'default', 'tfrc' for a credentials.tfrc.json document, 'env' for a TF_TOKEN_ environment variable, or 'all'.",
+				Default:     credsFormatDefault,
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ReadOperation: &framework.PathOperation{
@@ -72,38 +93,160 @@ func (b *tfBackend) pathCredentialsRead(ctx context.Context, req *logical.Reques
 		return nil, errors.New("error retrieving role: role is nil")
 	}
 
-	// If a user role was configured prior to 1.20, credentialType may not be set.
-	// This temporary setting does not persist to the role definition
-	if roleEntry.CredentialType == "" && roleEntry.UserID != "" {
-		b.Logger().Info("role's credential type not set in storage, inferring type \"user\".", "role", roleName)
-		roleEntry.CredentialType = userCredentialType
+	if resp, err := b.checkRoleAccess(req, roleEntry); resp != nil || err != nil {
+		return resp, err
 	}
 
-	if roleEntry.CredentialType == userCredentialType || roleEntry.CredentialType == teamCredentialType {
-		return b.createUserOrMultiTeamCreds(ctx, req, roleEntry)
+	if roleEntry.isStatic() {
+		return logical.ErrorResponse("role %q holds a static %s token, read it from static-creds/%s", roleName, roleEntry.CredentialType, roleName), nil
 	}
 
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"token_id":     roleEntry.TokenID,
-			"token":        roleEntry.Token,
-			"organization": roleEntry.Organization,
-			"team_id":      roleEntry.TeamID,
-			"role":         roleEntry.Name,
-		},
+	ctx = withRoleLogFields(ctx, req, roleEntry)
+	return b.createUserOrMultiTeamCreds(ctx, req, d, roleEntry)
+}
+
+// credsTTLs returns the lease TTL and max TTL for new credentials of the role,
+// applying the ttl and max_ttl requested on the creds path. Requested values
+// cannot exceed the max_ttl of the role, or the system max when the role has
+// none.
+func (b *tfBackend) credsTTLs(d *framework.FieldData, role *terraformRoleEntry) (ttl time.Duration, maxTTL time.Duration, err error) {
+	ttl, maxTTL = role.TTL, role.MaxTTL
+
+	limit := role.MaxTTL
+	if limit == 0 {
+		limit = b.System().MaxLeaseTTL()
 	}
 
-	if roleEntry.Description != "" {
-		resp.Data["description"] = roleEntry.Description
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		maxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+		if limit > 0 && maxTTL > limit {
+			return 0, 0, fmt.Errorf("max_ttl cannot be greater than %s", limit)
+		}
 	}
-	return resp, nil
+
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		ttl = time.Duration(ttlRaw.(int)) * time.Second
+		if limit > 0 && ttl > limit {
+			return 0, 0, fmt.Errorf("ttl cannot be greater than %s", limit)
+		}
+	}
+
+	if maxTTL > 0 && ttl > maxTTL {
+		return 0, 0, errors.New("ttl cannot be greater than max_ttl")
+	}
+
+	return ttl, maxTTL, nil
 }
 
-func (b *tfBackend) createUserOrMultiTeamCreds(ctx context.Context, req *logical.Request, role *terraformRoleEntry) (*logical.Response, error) {
-	token, err := b.createToken(ctx, req.Storage, role)
+func (b *tfBackend) createUserOrMultiTeamCreds(ctx context.Context, req *logical.Request, d *framework.FieldData, role *terraformRoleEntry) (*logical.Response, error) {
+	ttl, maxTTL, err := b.credsTTLs(d, role)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	format := d.Get("format").(string)
+	if !strutil.StrListContains(credsFormat_Values(), format) {
+		return logical.ErrorResponse("unrecognized format: %s", format), nil
+	}
+
+	var host, envVarName string
+	if format != credsFormatDefault {
+		config, err := getConfig(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return logical.ErrorResponse("backend is not configured"), nil
+		}
+
+		host, err = credentialsHost(config.Address)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		if format == credsFormatEnv || format == credsFormatAll {
+			envVarName, err = tokenEnvVarName(host)
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+	}
+
+	description, err := tokenDescription(req, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if suffix := d.Get("description_suffix").(string); suffix != "" {
+		description = strings.TrimSpace(description + " " + suffix)
+	}
+
+	opts := tokenOptions{
+		Description: description,
+	}
+
+	// requested TTLs override the upstream expiry, so a short lease does not
+	// come with a long-lived token; otherwise the expiry of the role is used
+	_, requestedMaxTTL := d.GetOk("max_ttl")
+	_, requestedTTL := d.GetOk("ttl")
+	if requestedMaxTTL {
+		opts.MaxTTL = maxTTL
+	}
+
+	leaseTTL := ttl
+	if leaseTTL == 0 {
+		leaseTTL = b.System().DefaultLeaseTTL()
+	}
+
+	switch {
+	case role.leaseBoundExpiry():
+		if expiry := role.leaseTokenExpiry(leaseTTL, maxTTL, time.Now()); opts.MaxTTL == 0 || expiry < opts.MaxTTL {
+			opts.MaxTTL = expiry
+		}
+	case requestedTTL && !requestedMaxTTL:
+		// the lease cannot be renewed past the expiry of the token
+		opts.MaxTTL = leaseTTL
+		maxTTL = leaseTTL
+	}
+
+	issuedAt := time.Now()
+	if resp, err := b.reserveRoleToken(ctx, req.Storage, role, issuedAt); resp != nil || err != nil {
+		return resp, err
+	}
+
+	token, err := b.createToken(ctx, req.Storage, role, opts)
+	if errors.Is(err, tfe.ErrResourceNotFound) && role.hasNamedTarget() {
+		// the team or user may have been recreated under the same name
+		if refreshed, refreshErr := b.refreshRoleIDs(ctx, req.Storage, role); refreshErr != nil {
+			b.Logger().Warn("unable to refresh resolved IDs for role", append([]interface{}{"error", refreshErr}, logFields(ctx)...)...)
+		} else if refreshed {
+			token, err = b.createToken(ctx, req.Storage, role, opts)
+		}
+	}
+	emitCredsMetric(role, err)
 	if err != nil {
+		if err := b.releaseRoleToken(ctx, req.Storage, role.Name, issuedAt); err != nil {
+			b.Logger().Warn("unable to release role usage", "role", role.Name, "error", err)
+		}
+		b.Logger().Error("unable to issue token", append([]interface{}{"error", err}, logFields(ctx)...)...)
+		b.recordError(statusOperationCreds, fmt.Errorf("role %q: %w", role.Name, err))
 		return nil, err
 	}
+	b.Logger().Debug("issued token", append([]interface{}{"token_id", token.ID, "expired_at", token.ExpiredAt}, logFields(ctx)...)...)
+
+	// the token exists now, so failing to record it must not fail the request
+	if err := b.recordIssuedToken(ctx, req.Storage, &issuedToken{
+		TokenID:        token.ID,
+		Role:           role.Name,
+		CredentialType: role.CredentialType,
+		EntityID:       req.EntityID,
+		RequestID:      req.ID,
+		IssuedAt:       issuedAt,
+		ExpiredAt:      token.ExpiredAt,
+		LeaseExpiresAt: b.leaseExpiresAt(issuedAt, maxTTL),
+	}, token.Token); err != nil {
+		b.Logger().Warn("unable to record issued token", append([]interface{}{"token_id", token.ID, "error", err}, logFields(ctx)...)...)
+	}
 
 	data := map[string]interface{}{
 		"token":    token.Token,
@@ -118,23 +261,66 @@ func (b *tfBackend) createUserOrMultiTeamCreds(ctx context.Context, req *logical
 		data["expired_at"] = token.ExpiredAt
 	}
 
-	resp := b.Secret(terraformTokenType).Response(data, map[string]interface{}{
-		"token_id": token.ID,
-		"role":     role.Name,
-	})
+	if format == credsFormatTFRC || format == credsFormatAll {
+		tfrc, err := credentialsTFRCJSON(host, token.Token)
+		if err != nil {
+			return nil, err
+		}
+		data["credentials_tfrc_json"] = tfrc
+	}
 
-	if role.TTL > 0 {
-		resp.Secret.TTL = role.TTL
+	if envVarName != "" {
+		data["tf_token_env_name"] = envVarName
+		data["tf_token_env_value"] = token.Token
 	}
 
-	if role.MaxTTL > 0 {
-		resp.Secret.MaxTTL = role.MaxTTL
+	internalData := map[string]interface{}{
+		"token_id":        token.ID,
+		"role":            role.Name,
+		"credential_type": role.CredentialType,
+		// leases issued before usage was tracked must not be released
+		"usage_tracked": true,
+	}
+
+	// Vault renews leases without the caller, so renewals check the bounds of
+	// the role against the caller recorded here
+	internalData["entity_id"] = req.EntityID
+	internalData["remote_addr"] = ""
+	if req.Connection != nil {
+		internalData["remote_addr"] = req.Connection.RemoteAddr
+	}
+
+	// renewals cap the lease at the expiry of the token
+	if !token.ExpiredAt.IsZero() {
+		internalData["expired_at"] = token.ExpiredAt.Format(time.RFC3339)
+	}
+
+	// keep requested TTLs so renewals honor them
+	if _, ok := d.GetOk("ttl"); ok {
+		internalData["ttl"] = ttl.Seconds()
+	}
+	if _, ok := d.GetOk("max_ttl"); ok {
+		internalData["max_ttl"] = maxTTL.Seconds()
+	}
+
+	b.sendEvent(ctx, eventTypeCredsIssue, tokenEventMetadata(role.Name, role.CredentialType, token.ID, token.ExpiredAt)...)
+
+	resp := b.Secret(terraformTokenType).Response(data, internalData)
+
+	if ttl > 0 {
+		resp.Secret.TTL = ttl
+	}
+
+	if maxTTL > 0 {
+		resp.Secret.MaxTTL = maxTTL
 	}
 
 	return resp, nil
 }
 
-func (b *tfBackend) createToken(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry) (*terraformToken, error) {
+// createToken creates a token for the role. The options are only used for
+// user and team tokens.
+func (b *tfBackend) createToken(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry, opts tokenOptions) (*terraformToken, error) {
 	client, err := b.getClient(ctx, s)
 	if err != nil {
 		return nil, err
@@ -147,13 +333,13 @@ func (b *tfBackend) createToken(ctx context.Context, s logical.Storage, roleEntr
 		token, err = createOrgToken(ctx, client, roleEntry.Organization)
 	case isTeamToken(roleEntry.TeamID):
 		if roleEntry.CredentialType == teamCredentialType {
-			token, err = createTeamTokenWithOptions(ctx, client, *roleEntry, b.System().MaxLeaseTTL())
+			token, err = createTeamTokenWithOptions(ctx, client, *roleEntry, opts, b.System().MaxLeaseTTL())
 		} else {
 			// team_legacy tokens
 			token, err = createTeamLegacyToken(ctx, client, roleEntry.TeamID)
 		}
 	default:
-		token, err = createUserToken(ctx, client, roleEntry.UserID, roleEntry.Description)
+		token, err = createUserToken(ctx, client, roleEntry.UserID, opts)
 	}
 
 	if err != nil {
@@ -172,15 +358,26 @@ Generate a Terraform Cloud or Enterprise API token from a specific Vault role.
 `
 
 const pathCredentialsHelpDesc = `
-This path generates Terraform Cloud or Enterprise API Organization, Team, or
-User Tokens based on a particular role. A role can only represent a single type
-of Token; Organization, Team, or User, and so can only contain one parameter for
-organization, team_id, or user_id.
+This path generates leased Terraform Cloud or Enterprise API Team or User
+Tokens based on a particular role. A role can only represent a single type
+of Token, and so can only contain one parameter for team_id or user_id.
 
-If the role has the team ID configured, this path generates a team token.
-
-If this role only has the organization configured, this path generates an
-organization token.
+If the role has the team ID configured with credential_type "team", this path
+generates a team token.
 
 If this role has a user ID configured, this path generates a user token.
+
+Organization and team_legacy roles hold a single stored token, which is read
+from "static-creds/" instead.
+
+The ttl and max_ttl can be set for a single request within the max_ttl of the
+role, for example to issue short-lived tokens to CI jobs. A requested max_ttl,
+or else a requested ttl, also sets the expiry of the token in Terraform Cloud /
+Enterprise. A description_suffix
+is appended to the description of the token.
+
+Set format to "tfrc" to also return a credentials.tfrc.json document for the
+configured address as credentials_tfrc_json, to "env" to also return the name
+and value of the matching TF_TOKEN_ environment variable as tf_token_env_name
+and tf_token_env_value, or to "all" for both.
 `
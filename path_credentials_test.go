@@ -5,13 +5,18 @@ package tfc
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"os"
 	"testing"
 	"time"
 
 	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/logging"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
 )
 
 func newAcceptanceTestEnv() (*testEnv, error) {
@@ -105,3 +110,257 @@ func TestAcceptanceUserToken(t *testing.T) {
 	t.Run("read user token cred", acceptanceTestEnv.ReadUserToken)
 	t.Run("cleanup user tokens", acceptanceTestEnv.CleanupUserTokens)
 }
+
+func TestCredsTTLs(t *testing.T) {
+	b, _ := getTestBackend(t)
+	path := pathCredentials(b)
+
+	role := &terraformRoleEntry{
+		TTL:    time.Hour,
+		MaxTTL: 8 * time.Hour,
+	}
+
+	cases := map[string]struct {
+		data           map[string]interface{}
+		expectedTTL    time.Duration
+		expectedMaxTTL time.Duration
+		expectedErr    string
+	}{
+		"role defaults": {
+			data:           map[string]interface{}{},
+			expectedTTL:    time.Hour,
+			expectedMaxTTL: 8 * time.Hour,
+		},
+		"shorter ttl and max_ttl": {
+			data:           map[string]interface{}{"ttl": "10m", "max_ttl": "10m"},
+			expectedTTL:    10 * time.Minute,
+			expectedMaxTTL: 10 * time.Minute,
+		},
+		"max_ttl above role": {
+			data:        map[string]interface{}{"max_ttl": "9h"},
+			expectedErr: "max_ttl cannot be greater than",
+		},
+		"ttl above role": {
+			data:        map[string]interface{}{"ttl": "9h"},
+			expectedErr: "ttl cannot be greater than",
+		},
+		"ttl above requested max_ttl": {
+			data:        map[string]interface{}{"ttl": "2h", "max_ttl": "1h"},
+			expectedErr: "ttl cannot be greater than max_ttl",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := &framework.FieldData{Raw: tc.data, Schema: path.Fields}
+			ttl, maxTTL, err := b.credsTTLs(d, role)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedTTL, ttl)
+			require.Equal(t, tc.expectedMaxTTL, maxTTL)
+		})
+	}
+}
+
+func TestCredsRequestedTTLsSetTokenExpiry(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	// the server echoes the requested expiry of the token
+	createToken := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Data struct {
+				Attributes struct {
+					ExpiredAt *time.Time `json:"expired-at"`
+				} `json:"attributes"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		attributes := `"token":"new-token"`
+		if body.Data.Attributes.ExpiredAt != nil {
+			attributes += fmt.Sprintf(`,"expired-at":%q`, body.Data.Attributes.ExpiredAt.UTC().Format(time.RFC3339))
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data":{"id":"at-new","type":"authentication-tokens","attributes":{%s}}}`, attributes)
+	}
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"POST /api/v2/users/user-123/authentication-tokens": createToken,
+		"POST /api/v2/teams/team-123/authentication-tokens": createToken,
+	})
+
+	require.NoError(t, setRole(ctx, s, "user", &terraformRoleEntry{
+		Name:           "user",
+		UserID:         "user-123",
+		CredentialType: userCredentialType,
+		TTL:            time.Hour,
+		MaxTTL:         8 * time.Hour,
+	}))
+	require.NoError(t, setRole(ctx, s, "team", &terraformRoleEntry{
+		Name:           "team",
+		TeamID:         "team-123",
+		CredentialType: teamCredentialType,
+		TTL:            time.Hour,
+		MaxTTL:         8 * time.Hour,
+	}))
+
+	cases := map[string]struct {
+		data           map[string]interface{}
+		expectedExpiry time.Duration
+	}{
+		"ttl only": {
+			data:           map[string]interface{}{"ttl": "10m"},
+			expectedExpiry: 10 * time.Minute,
+		},
+		"max_ttl only": {
+			data:           map[string]interface{}{"max_ttl": "2h"},
+			expectedExpiry: 2 * time.Hour,
+		},
+		"ttl and max_ttl": {
+			data:           map[string]interface{}{"ttl": "10m", "max_ttl": "2h"},
+			expectedExpiry: 2 * time.Hour,
+		},
+	}
+
+	for _, role := range []string{"user", "team"} {
+		for name, tc := range cases {
+			t.Run(role+" "+name, func(t *testing.T) {
+				resp, err := b.HandleRequest(ctx, &logical.Request{
+					Operation: logical.UpdateOperation,
+					Path:      "creds/" + role,
+					Storage:   s,
+					Data:      tc.data,
+				})
+				require.NoError(t, err)
+				require.False(t, resp.IsError())
+
+				expiredAt, ok := resp.Data["expired_at"].(time.Time)
+				require.True(t, ok)
+				require.WithinDuration(t, time.Now().Add(tc.expectedExpiry), expiredAt, 5*time.Second)
+				require.LessOrEqual(t, resp.Secret.MaxTTL, tc.expectedExpiry)
+			})
+		}
+	}
+}
+
+func TestRenewHonorsRequestedTTLs(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	err := setRole(ctx, s, roleName, &terraformRoleEntry{
+		Name:           roleName,
+		UserID:         "user-123",
+		CredentialType: userCredentialType,
+		TTL:            time.Hour,
+		MaxTTL:         8 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	resp, err := b.terraformTokenRenew(ctx, &logical.Request{
+		Storage: s,
+		Secret: &logical.Secret{
+			InternalData: map[string]interface{}{
+				"role":    roleName,
+				"ttl":     float64(600),
+				"max_ttl": float64(600),
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, resp.Secret.TTL)
+	require.Equal(t, 10*time.Minute, resp.Secret.MaxTTL)
+}
+
+func TestRenewVerifiesUpstreamToken(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	expiredAt := time.Now().Add(30 * time.Minute).UTC().Truncate(time.Second)
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/authentication-tokens/at-live": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"data":{"id":"at-live","type":"authentication-tokens","attributes":{"expired-at":%q}}}`, expiredAt.Format(time.RFC3339))
+		},
+		"/api/v2/authentication-tokens/at-forever": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"at-forever","type":"authentication-tokens","attributes":{}}}`)
+		},
+	})
+
+	err := setRole(ctx, s, roleName, &terraformRoleEntry{
+		Name:           roleName,
+		UserID:         "user-123",
+		CredentialType: userCredentialType,
+		TTL:            time.Hour,
+		MaxTTL:         8 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	renew := func(internalData map[string]interface{}) (*logical.Response, error) {
+		internalData["role"] = roleName
+		secret := &logical.Secret{InternalData: internalData}
+		secret.IssueTime = time.Now().Add(-time.Minute)
+		return b.terraformTokenRenew(ctx, &logical.Request{
+			Storage: s,
+			Secret:  secret,
+		}, nil)
+	}
+
+	t.Run("lease capped at token expiry", func(t *testing.T) {
+		resp, err := renew(map[string]interface{}{
+			"token_id": "at-live",
+		})
+		require.NoError(t, err)
+		require.LessOrEqual(t, resp.Secret.TTL, 30*time.Minute)
+		require.Greater(t, resp.Secret.TTL, 29*time.Minute)
+		require.LessOrEqual(t, resp.Secret.MaxTTL, 31*time.Minute)
+	})
+
+	t.Run("token without expiry", func(t *testing.T) {
+		resp, err := renew(map[string]interface{}{
+			"token_id": "at-forever",
+		})
+		require.NoError(t, err)
+		require.Equal(t, time.Hour, resp.Secret.TTL)
+		require.Equal(t, 8*time.Hour, resp.Secret.MaxTTL)
+	})
+
+	t.Run("deleted token - fail", func(t *testing.T) {
+		_, err := renew(map[string]interface{}{
+			"token_id": "at-gone",
+		})
+		require.ErrorContains(t, err, "no longer exists")
+	})
+
+	t.Run("expired token - fail", func(t *testing.T) {
+		_, err := renew(map[string]interface{}{
+			"expired_at": time.Now().Add(-time.Minute).Format(time.RFC3339),
+		})
+		require.ErrorContains(t, err, "expired at")
+	})
+}
+
+func TestRevokeTokenExpiredUpstream(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	// every token has expired upstream, so deleting it is not found
+	newTestServer(t, b, s, nil)
+
+	cases := map[string]map[string]interface{}{
+		"user token":         {"token_id": "at-expired"},
+		"organization token": {"organization": "acme"},
+		"team token":         {"team_id": "team-123"},
+	}
+
+	for name, internalData := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := b.terraformTokenRevoke(ctx, &logical.Request{
+				Storage: s,
+				Secret:  &logical.Secret{InternalData: internalData},
+			}, nil)
+			require.NoError(t, err)
+		})
+	}
+}
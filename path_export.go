@@ -0,0 +1,135 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// exportDocumentVersion is the version of the document produced by "export"
+// and accepted by "import". Bump it when the layout of the document changes.
+const exportDocumentVersion = 1
+
+func pathExport(b *tfBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "export",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTerraformCloud,
+			OperationVerb:   "export",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"include_tokens": {
+				Type:        framework.TypeBool,
+				Description: "Include the tokens stored on organization and team_legacy roles, so they can be imported without creating new ones.",
+				Default:     false,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathExportRead,
+			},
+		},
+
+		HelpSynopsis:    pathExportHelpSyn,
+		HelpDescription: pathExportHelpDesc,
+	}
+}
+
+func (b *tfBackend) pathExportRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	includeTokens := d.Get("include_tokens").(bool)
+
+	names, err := req.Storage.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		roleEntry, err := b.getRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if roleEntry == nil {
+			continue
+		}
+
+		roleData, err := roleExportData(roleEntry, includeTokens)
+		if err != nil {
+			return nil, err
+		}
+		roles[name] = roleData
+	}
+
+	data := map[string]interface{}{
+		"version":         exportDocumentVersion,
+		"storage_version": currentStorageVersion(),
+		"exported_at":     time.Now().UTC(),
+		"includes_tokens": includeTokens,
+		"roles":           roles,
+	}
+
+	config, err := getConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		// the token of the config is never exported
+		data["config"] = map[string]interface{}{
+			"address":   config.Address,
+			"base_path": config.BasePath,
+		}
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// roleExportData returns the role as it is stored, without the state Vault
+// keeps about its token. The stored token is only included if includeTokens
+// is set.
+func roleExportData(roleEntry *terraformRoleEntry, includeTokens bool) (map[string]interface{}, error) {
+	exported := *roleEntry
+	exported.NextRotation = time.Time{}
+	exported.SyncStatus = nil
+	// hashes depend on the salt of the mount
+	exported.TokenHash = ""
+	if !includeTokens {
+		exported.Token = ""
+		exported.TokenID = ""
+		exported.LastRotated = time.Time{}
+	}
+
+	encoded, err := jsonutil.EncodeJSON(&exported)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	if err := jsonutil.DecodeJSON(encoded, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+const (
+	pathExportHelpSyn  = `Export the roles and connection settings of the backend.`
+	pathExportHelpDesc = `
+This path returns a versioned document holding every role and the address and
+base_path of the config, which can be applied to another mount through
+"import". The token of the config is never exported. The tokens stored on
+organization and team_legacy roles are only exported if include_tokens is set.
+
+Roles are exported as they are stored, so durations such as ttl are in
+nanoseconds. The document is meant to be passed to "import" as is.
+`
+)
@@ -0,0 +1,384 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	importConflictSkip      = "skip"
+	importConflictOverwrite = "overwrite"
+	importConflictFail      = "fail"
+)
+
+func importConflictPolicy_Values() []string {
+	return []string{
+		importConflictSkip,
+		importConflictOverwrite,
+		importConflictFail,
+	}
+}
+
+const (
+	importStatusCreated     = "created"
+	importStatusOverwritten = "overwritten"
+	importStatusSkipped     = "skipped"
+	importStatusUnchanged   = "unchanged"
+	importStatusFailed      = "failed"
+
+	importTokenImported = "imported"
+	importTokenMinted   = "minted"
+	importTokenMissing  = "missing"
+)
+
+func pathImport(b *tfBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "import",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTerraformCloud,
+			OperationVerb:   "import",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"version": {
+				Type:        framework.TypeInt,
+				Description: "Version of the document, as returned by export.",
+				Required:    true,
+			},
+			"roles": {
+				Type:        framework.TypeMap,
+				Description: "Roles to import, keyed by name, as returned by export.",
+			},
+			"config": {
+				Type:        framework.TypeMap,
+				Description: "Address and base_path of the config, as returned by export.",
+			},
+			"storage_version": {
+				Type:        framework.TypeInt,
+				Description: "Storage version of the exporting mount. Informational only.",
+			},
+			"exported_at": {
+				Type:        framework.TypeString,
+				Description: "Time the document was exported. Informational only.",
+			},
+			"includes_tokens": {
+				Type:        framework.TypeBool,
+				Description: "Whether the document holds the tokens of organization and team_legacy roles. Informational only.",
+			},
+			"conflict_policy": {
+				Type:        framework.TypeString,
+				Description: "What to do with roles and config that already exist. Can be 'skip', 'overwrite' or 'fail'. With 'fail', nothing is imported if anything already exists.",
+				Default:     importConflictFail,
+			},
+			"mint_tokens": {
+				Type:        framework.TypeBool,
+				Description: "Create new tokens for imported organization and team_legacy roles instead of keeping the tokens in the document. Creating a token revokes the previous token of the organization or team. The new token of a role with store_token_hash is returned once, as new_token.",
+				Default:     false,
+			},
+			"dry_run": {
+				Type:        framework.TypeBool,
+				Description: "Report what the import would do without changing anything.",
+				Default:     false,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathImportWrite,
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathImportHelpSyn,
+		HelpDescription: pathImportHelpDesc,
+	}
+}
+
+func (b *tfBackend) pathImportWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if version := d.Get("version").(int); version != exportDocumentVersion {
+		return logical.ErrorResponse("unsupported document version %d, expected %d", version, exportDocumentVersion), nil
+	}
+
+	conflictPolicy := d.Get("conflict_policy").(string)
+	if !strutil.StrListContains(importConflictPolicy_Values(), conflictPolicy) {
+		return logical.ErrorResponse("unrecognized conflict_policy: %s", conflictPolicy), nil
+	}
+
+	mintTokens := d.Get("mint_tokens").(bool)
+	dryRun := d.Get("dry_run").(bool)
+
+	roles := make(map[string]*terraformRoleEntry)
+	roleResults := make(map[string]interface{})
+	for name, raw := range d.Get("roles").(map[string]interface{}) {
+		roleEntry, err := decodeImportedRole(name, raw)
+		if err != nil {
+			roleResults[name] = map[string]interface{}{
+				"status": importStatusFailed,
+				"error":  err.Error(),
+			}
+			continue
+		}
+		roles[name] = roleEntry
+	}
+
+	names := make([]string, 0, len(roles))
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		roleEntry, err := b.getRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		existing[name] = roleEntry != nil
+	}
+
+	config, err := getConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var importedConfig *tfConfig
+	configStatus := ""
+	if rawConfig, ok := d.GetOk("config"); ok {
+		importedConfig, err = decodeImportedConfig(rawConfig.(map[string]interface{}))
+		if err != nil {
+			return logical.ErrorResponse("invalid config: %s", err), nil
+		}
+
+		switch {
+		case config == nil:
+			configStatus = importStatusCreated
+		case config.Address == importedConfig.Address && config.BasePath == importedConfig.BasePath:
+			configStatus = importStatusUnchanged
+		case conflictPolicy == importConflictOverwrite:
+			configStatus = importStatusOverwritten
+		default:
+			configStatus = importStatusSkipped
+		}
+	}
+
+	if conflictPolicy == importConflictFail {
+		var conflicts []string
+		for _, name := range names {
+			if existing[name] {
+				conflicts = append(conflicts, "role/"+name)
+			}
+		}
+		if configStatus == importStatusSkipped {
+			conflicts = append(conflicts, configStoragePath)
+		}
+		if len(conflicts) > 0 {
+			return logical.ErrorResponse("import aborted, already exists: %s", strings.Join(conflicts, ", ")), nil
+		}
+	}
+
+	var warnings []string
+
+	if !dryRun && (configStatus == importStatusCreated || configStatus == importStatusOverwritten) {
+		if config == nil {
+			config = new(tfConfig)
+			warnings = append(warnings, "config was imported without a token, set one with config before using the backend")
+		}
+		config.Address = importedConfig.Address
+		config.BasePath = importedConfig.BasePath
+
+		entry, err := logical.StorageEntryJSON(configStoragePath, config)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Storage.Put(ctx, entry); err != nil {
+			return nil, err
+		}
+
+		// reset the client so static role tokens are minted with the new
+		// configuration
+		b.reset()
+	}
+
+	for _, name := range names {
+		roleEntry := roles[name]
+		result := map[string]interface{}{
+			"status":          importStatusCreated,
+			"credential_type": roleEntry.CredentialType,
+		}
+		roleResults[name] = result
+
+		if existing[name] {
+			if conflictPolicy == importConflictSkip {
+				result["status"] = importStatusSkipped
+				continue
+			}
+			result["status"] = importStatusOverwritten
+		}
+
+		if roleEntry.isStatic() {
+			switch {
+			case mintTokens:
+				result["token"] = importTokenMinted
+			case roleEntry.Token != "":
+				result["token"] = importTokenImported
+			default:
+				result["token"] = importTokenMissing
+				warnings = append(warnings, fmt.Sprintf("role %q was imported without a token, rotate it with rotate-role/%s", name, name))
+			}
+		}
+
+		if dryRun {
+			continue
+		}
+
+		if err := b.storeImportedRole(ctx, req.Storage, roleEntry, mintTokens); err != nil {
+			// keep going, one failing role must not block the others
+			b.Logger().Error("unable to import role", "role", name, "error", err)
+			result["status"] = importStatusFailed
+			result["error"] = err.Error()
+			delete(result, "token")
+			continue
+		}
+
+		// the import response is the only place a minted token of a role
+		// with store_token_hash can be read from
+		if hashed := roleEntry.hashedTokenResponse(); hashed != nil && mintTokens {
+			result["new_token"] = hashed.Data["token"]
+			warnings = append(warnings, hashed.Warnings...)
+		}
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"dry_run": dryRun,
+			"roles":   roleResults,
+		},
+	}
+	if configStatus != "" {
+		resp.Data["config"] = configStatus
+	}
+	for _, warning := range warnings {
+		resp.AddWarning(warning)
+	}
+
+	return resp, nil
+}
+
+// decodeImportedRole decodes and checks a role of an export document. Only
+// checks that do not need the Terraform API are made, so a document can be
+// imported before the backend is configured.
+func decodeImportedRole(name string, raw interface{}) (*terraformRoleEntry, error) {
+	encoded, err := jsonutil.EncodeJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	roleEntry := new(terraformRoleEntry)
+	if err := jsonutil.DecodeJSON(encoded, roleEntry); err != nil {
+		return nil, fmt.Errorf("error decoding role: %w", err)
+	}
+
+	roleEntry.Name = name
+	roleEntry.SyncStatus = nil
+	roleEntry.NextRotation = time.Time{}
+
+	if !roleEntry.isStatic() {
+		roleEntry.Token = ""
+		roleEntry.TokenID = ""
+	}
+
+	// hold the role to the checks of a role write, so it cannot fail only
+	// once credentials are requested
+	if err := roleEntry.validate(); err != nil {
+		return nil, err
+	}
+
+	// hashes depend on the salt of the mount the document was exported from
+	roleEntry.TokenHash = ""
+
+	return roleEntry, nil
+}
+
+func decodeImportedConfig(raw map[string]interface{}) (*tfConfig, error) {
+	config := &tfConfig{
+		Address:  defaultAddress,
+		BasePath: defaultBasePath,
+	}
+
+	if address, ok := raw["address"].(string); ok && address != "" {
+		config.Address = address
+	}
+	if basePath, ok := raw["base_path"].(string); ok && basePath != "" {
+		config.BasePath = basePath
+	}
+
+	if _, ok := raw["token"]; ok {
+		return nil, errors.New("token cannot be imported, set it with config")
+	}
+
+	return config, nil
+}
+
+// storeImportedRole persists an imported role. Organization and team_legacy
+// roles keep the token of the document, unless mintTokens is set, in which
+// case a new token is created for them.
+func (b *tfBackend) storeImportedRole(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry, mintTokens bool) error {
+	lock := locksutil.LockForKey(b.roleLocks, roleEntry.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ctx = withRoleLogFields(ctx, nil, roleEntry)
+
+	if roleEntry.isStatic() && mintTokens {
+		return b.storeRoleWithToken(ctx, s, roleEntry)
+	}
+
+	if roleEntry.isStatic() && !roleEntry.LastRotated.IsZero() {
+		next, err := roleEntry.nextRotationAfter(roleEntry.LastRotated)
+		if err != nil {
+			return err
+		}
+		roleEntry.NextRotation = next
+	}
+
+	if err := b.setRoleTokenHash(ctx, s, roleEntry); err != nil {
+		return err
+	}
+
+	return setRole(ctx, s, roleEntry.Name, roleEntry)
+}
+
+const (
+	pathImportHelpSyn  = `Import roles and connection settings exported from a backend.`
+	pathImportHelpDesc = `
+This path applies a document returned by "export", for example to recreate a
+mount for disaster recovery or to move it to another namespace. Roles are
+stored as they are in the document; they are not checked against Terraform
+Cloud / Enterprise, and no tokens are created unless mint_tokens is set.
+Organization and team_legacy roles exported without their token are imported
+without one and should be rotated through "rotate-role/". Tokens minted for
+roles with store_token_hash are returned once, as new_token in the result of
+the role.
+
+conflict_policy decides what happens to roles and config that already exist:
+"skip" leaves them alone, "overwrite" replaces them, and "fail" (the default)
+aborts the whole import before anything is written. The token of an existing
+config is always kept. Set dry_run to see the outcome for each role without
+changing anything.
+`
+)
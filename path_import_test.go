@@ -0,0 +1,325 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	lastRotated := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	roles := []*terraformRoleEntry{
+		{
+			Name:           "org",
+			Organization:   "acme",
+			CredentialType: organizationCredentialType,
+			Token:          "org-token",
+			TokenID:        "at-org",
+			RotationPeriod: 24 * time.Hour,
+			LastRotated:    lastRotated,
+			NextRotation:   lastRotated.Add(24 * time.Hour),
+			Tags:           []string{"prod"},
+		},
+		{
+			Name:           "team",
+			Organization:   "acme",
+			TeamID:         "team-123",
+			CredentialType: teamCredentialType,
+			Description:    "ci",
+			TTL:            time.Hour,
+			MaxTTL:         2 * time.Hour,
+		},
+		{
+			Name:           "user",
+			UserID:         "user-123",
+			CredentialType: userCredentialType,
+			Metadata:       map[string]string{"owner": "platform"},
+		},
+	}
+	for _, roleEntry := range roles {
+		require.NoError(t, setRole(ctx, s, roleEntry.Name, roleEntry))
+	}
+
+	entry, err := logical.StorageEntryJSON(configStoragePath, &tfConfig{
+		Token:    "config-token",
+		Address:  "https://tfe.example.com",
+		BasePath: defaultBasePath,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Put(ctx, entry))
+
+	// export returns the document the way it comes back over the HTTP API
+	export := func(t *testing.T, includeTokens bool) map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "export",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"include_tokens": includeTokens,
+			},
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		encoded, err := jsonutil.EncodeJSON(resp.Data)
+		require.NoError(t, err)
+
+		var doc map[string]interface{}
+		require.NoError(t, jsonutil.DecodeJSON(encoded, &doc))
+		return doc
+	}
+
+	importInto := func(t *testing.T, target logical.Storage, targetBackend *tfBackend, doc map[string]interface{}, options map[string]interface{}) *logical.Response {
+		t.Helper()
+		data := make(map[string]interface{}, len(doc)+len(options))
+		for k, v := range doc {
+			data[k] = v
+		}
+		for k, v := range options {
+			data[k] = v
+		}
+
+		resp, err := targetBackend.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "import",
+			Storage:   target,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("export", func(t *testing.T) {
+		doc := export(t, false)
+		require.Equal(t, json.Number("1"), doc["version"])
+		require.Equal(t, map[string]interface{}{
+			"address":   "https://tfe.example.com",
+			"base_path": defaultBasePath,
+		}, doc["config"])
+		require.NotContains(t, doc["config"], "token")
+
+		exported := doc["roles"].(map[string]interface{})
+		require.Len(t, exported, 3)
+		require.NotContains(t, exported["org"], "token")
+		require.NotContains(t, exported["org"], "token_id")
+
+		doc = export(t, true)
+		exported = doc["roles"].(map[string]interface{})
+		require.Equal(t, "org-token", exported["org"].(map[string]interface{})["token"])
+	})
+
+	t.Run("import into empty mount", func(t *testing.T) {
+		target, targetStorage := getTestBackend(t)
+
+		resp := importInto(t, targetStorage, target, export(t, true), nil)
+		require.False(t, resp.IsError())
+		require.Equal(t, importStatusCreated, resp.Data["config"])
+		results := resp.Data["roles"].(map[string]interface{})
+		require.Equal(t, importTokenImported, results["org"].(map[string]interface{})["token"])
+		require.Equal(t, importStatusCreated, results["user"].(map[string]interface{})["status"])
+
+		for _, expected := range roles {
+			roleEntry, err := target.getRole(ctx, targetStorage, expected.Name)
+			require.NoError(t, err)
+			require.NotNil(t, roleEntry)
+			require.Equal(t, expected.CredentialType, roleEntry.CredentialType)
+			require.Equal(t, expected.TTL, roleEntry.TTL)
+			require.Equal(t, expected.MaxTTL, roleEntry.MaxTTL)
+			require.Equal(t, expected.Token, roleEntry.Token)
+			require.Equal(t, expected.Tags, roleEntry.Tags)
+			require.Equal(t, expected.Metadata, roleEntry.Metadata)
+		}
+
+		roleEntry, err := target.getRole(ctx, targetStorage, "org")
+		require.NoError(t, err)
+		require.True(t, roleEntry.NextRotation.Equal(lastRotated.Add(24*time.Hour)))
+
+		config, err := getConfig(ctx, targetStorage)
+		require.NoError(t, err)
+		require.Equal(t, "https://tfe.example.com", config.Address)
+		require.Empty(t, config.Token)
+	})
+
+	t.Run("import without tokens", func(t *testing.T) {
+		target, targetStorage := getTestBackend(t)
+
+		resp := importInto(t, targetStorage, target, export(t, false), nil)
+		require.False(t, resp.IsError())
+		results := resp.Data["roles"].(map[string]interface{})
+		require.Equal(t, importTokenMissing, results["org"].(map[string]interface{})["token"])
+		require.NotEmpty(t, resp.Warnings)
+
+		roleEntry, err := target.getRole(ctx, targetStorage, "org")
+		require.NoError(t, err)
+		require.Empty(t, roleEntry.Token)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		target, targetStorage := getTestBackend(t)
+
+		resp := importInto(t, targetStorage, target, export(t, true), map[string]interface{}{
+			"dry_run": true,
+		})
+		require.False(t, resp.IsError())
+		require.Len(t, resp.Data["roles"], 3)
+
+		keys, err := targetStorage.List(ctx, "role/")
+		require.NoError(t, err)
+		require.Empty(t, keys)
+
+		config, err := getConfig(ctx, targetStorage)
+		require.NoError(t, err)
+		require.Nil(t, config)
+	})
+
+	t.Run("conflict policies", func(t *testing.T) {
+		target, targetStorage := getTestBackend(t)
+		require.NoError(t, setRole(ctx, targetStorage, "user", &terraformRoleEntry{
+			Name:           "user",
+			UserID:         "user-999",
+			CredentialType: userCredentialType,
+		}))
+		doc := export(t, true)
+
+		resp := importInto(t, targetStorage, target, doc, nil)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "role/user")
+		keys, err := targetStorage.List(ctx, "role/")
+		require.NoError(t, err)
+		require.Equal(t, []string{"user"}, keys)
+
+		resp = importInto(t, targetStorage, target, doc, map[string]interface{}{
+			"conflict_policy": importConflictSkip,
+		})
+		require.False(t, resp.IsError())
+		results := resp.Data["roles"].(map[string]interface{})
+		require.Equal(t, importStatusSkipped, results["user"].(map[string]interface{})["status"])
+		roleEntry, err := target.getRole(ctx, targetStorage, "user")
+		require.NoError(t, err)
+		require.Equal(t, "user-999", roleEntry.UserID)
+
+		resp = importInto(t, targetStorage, target, doc, map[string]interface{}{
+			"conflict_policy": importConflictOverwrite,
+		})
+		require.False(t, resp.IsError())
+		results = resp.Data["roles"].(map[string]interface{})
+		require.Equal(t, importStatusOverwritten, results["user"].(map[string]interface{})["status"])
+		require.Equal(t, importStatusUnchanged, resp.Data["config"])
+		roleEntry, err = target.getRole(ctx, targetStorage, "user")
+		require.NoError(t, err)
+		require.Equal(t, "user-123", roleEntry.UserID)
+	})
+
+	t.Run("mint tokens of roles with store_token_hash", func(t *testing.T) {
+		target, targetStorage := getTestBackend(t)
+		newTestServer(t, target, targetStorage, map[string]http.HandlerFunc{
+			"/api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+			},
+			"POST /api/v2/organizations/acme/authentication-token": func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"data":{"id":"at-minted","type":"authentication-tokens","attributes":{"token":"minted-token"}}}`)
+			},
+		})
+
+		resp := importInto(t, targetStorage, target, map[string]interface{}{
+			"version": exportDocumentVersion,
+			"roles": map[string]interface{}{
+				"hashed": map[string]interface{}{
+					"credential_type":  organizationCredentialType,
+					"organization":     "acme",
+					"store_token_hash": true,
+				},
+			},
+		}, map[string]interface{}{
+			"mint_tokens": true,
+		})
+		require.False(t, resp.IsError())
+		result := resp.Data["roles"].(map[string]interface{})["hashed"].(map[string]interface{})
+		require.Equal(t, importTokenMinted, result["token"])
+		require.Equal(t, "minted-token", result["new_token"])
+		require.NotEmpty(t, resp.Warnings)
+
+		roleEntry, err := target.getRole(ctx, targetStorage, "hashed")
+		require.NoError(t, err)
+		require.Equal(t, "at-minted", roleEntry.TokenID)
+		require.Empty(t, roleEntry.Token)
+		require.NotEmpty(t, roleEntry.TokenHash)
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		target, targetStorage := getTestBackend(t)
+
+		doc := export(t, false)
+		doc["version"] = exportDocumentVersion + 1
+		resp := importInto(t, targetStorage, target, doc, nil)
+		require.True(t, resp.IsError())
+
+		// roles a role write would reject are not imported either
+		invalid := map[string]map[string]interface{}{
+			"unknown credential type": {
+				"credential_type": "unknown",
+				"organization":    "acme",
+			},
+			"ttl above max_ttl": {
+				"credential_type": teamCredentialType,
+				"team_id":         "team-123",
+				"ttl":             2 * time.Hour,
+				"max_ttl":         time.Hour,
+			},
+			"quota without window": {
+				"credential_type":     userCredentialType,
+				"user_id":             "user-123",
+				"issuance_rate_limit": 5,
+			},
+			"lease expiry on user role": {
+				"credential_type":   userCredentialType,
+				"user_id":           "user-123",
+				"team_token_expiry": teamTokenExpiryLease,
+			},
+			"invalid description_template": {
+				"credential_type":      teamCredentialType,
+				"team_id":              "team-123",
+				"description_template": "{{ .Missing",
+			},
+			"description on team_legacy role": {
+				"credential_type": teamLegacyCredentialType,
+				"organization":    "acme",
+				"team_id":         "team-123",
+				"description":     "ci",
+			},
+		}
+		doc = map[string]interface{}{
+			"version": exportDocumentVersion,
+			"roles":   map[string]interface{}{},
+		}
+		for name, role := range invalid {
+			doc["roles"].(map[string]interface{})[name] = role
+		}
+
+		resp = importInto(t, targetStorage, target, doc, nil)
+		require.False(t, resp.IsError())
+		results := resp.Data["roles"].(map[string]interface{})
+		for name := range invalid {
+			require.Equal(t, importStatusFailed, results[name].(map[string]interface{})["status"], name)
+		}
+
+		keys, err := targetStorage.List(ctx, "role/")
+		require.NoError(t, err)
+		require.Empty(t, keys)
+	})
+}
@@ -0,0 +1,279 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathLookup(b *tfBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "lookup",
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTerraformCloud,
+			OperationVerb:   "lookup",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"token": {
+				Type:        framework.TypeString,
+				Description: "The Terraform Cloud / Enterprise token to look up. It is never returned.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+			"token_id": {
+				Type:        framework.TypeString,
+				Description: "The ID of the token to look up, if the token itself is not known.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathLookupWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "token",
+				},
+			},
+		},
+		HelpSynopsis:    pathLookupHelpSyn,
+		HelpDescription: pathLookupHelpDesc,
+	}
+}
+
+func (b *tfBackend) pathLookupWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	token := d.Get("token").(string)
+	tokenID := d.Get("token_id").(string)
+
+	if (token == "") == (tokenID == "") {
+		return logical.ErrorResponse("exactly one of token or token_id must be provided"), nil
+	}
+
+	data := map[string]interface{}{
+		"issued_by_mount": false,
+	}
+
+	var credentialType string
+	if token != "" {
+		issuedTokenID, err := b.issuedTokenIDForToken(ctx, req.Storage, token)
+		if err != nil {
+			return nil, err
+		}
+		tokenID = issuedTokenID
+
+		if tokenID == "" {
+			roleEntry, err := b.staticRoleForToken(ctx, req.Storage, token)
+			if err != nil {
+				return nil, err
+			}
+			if roleEntry != nil {
+				tokenID = roleEntry.TokenID
+				credentialType = roleEntry.CredentialType
+				data["issued_by_mount"] = true
+				data["static"] = true
+				data["role"] = roleEntry.Name
+				data["credential_type"] = roleEntry.CredentialType
+				if !roleEntry.LastRotated.IsZero() {
+					data["issued_at"] = roleEntry.LastRotated.Format(time.RFC3339)
+				}
+			}
+		}
+
+		owner, err := b.lookupTokenOwner(ctx, req.Storage, token)
+		if err != nil {
+			return nil, err
+		}
+		data["owner"] = owner
+	}
+
+	if tokenID != "" {
+		record, err := getIssuedToken(ctx, req.Storage, tokenID)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			for k, v := range record.toResponseData() {
+				data[k] = v
+			}
+			data["issued_by_mount"] = true
+			data["static"] = false
+			credentialType = record.CredentialType
+		}
+
+		data["token_id"] = tokenID
+
+		upstream, err := b.lookupUpstreamToken(ctx, req.Storage, tokenID, credentialType)
+		if err != nil {
+			return nil, err
+		}
+		data["upstream"] = upstream
+	}
+
+	return &logical.Response{Data: data}, nil
+}
+
+// staticRoleForToken returns the organization or team_legacy role holding the
+// token, if any.
+func (b *tfBackend) staticRoleForToken(ctx context.Context, s logical.Storage, token string) (*terraformRoleEntry, error) {
+	names, err := s.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+
+	var hash string
+	for _, name := range names {
+		roleEntry, err := b.getRole(ctx, s, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if roleEntry == nil || !roleEntry.isStatic() {
+			continue
+		}
+
+		stored, candidate := roleEntry.Token, token
+		if roleEntry.TokenHash != "" {
+			if hash == "" {
+				if hash, err = b.hashToken(ctx, s, token); err != nil {
+					return nil, err
+				}
+			}
+			stored, candidate = roleEntry.TokenHash, hash
+		}
+
+		if stored != "" && subtle.ConstantTimeCompare([]byte(stored), []byte(candidate)) == 1 {
+			return roleEntry, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// lookupTokenOwner reads the account the token belongs to, using the token
+// itself.
+func (b *tfBackend) lookupTokenOwner(ctx context.Context, s logical.Storage, token string) (map[string]interface{}, error) {
+	config, err := getConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, errors.New("backend is not configured")
+	}
+
+	tokenClient, err := newClient(&tfConfig{
+		Address:  config.Address,
+		BasePath: config.BasePath,
+		Token:    token,
+	}, b.Logger())
+	if err != nil {
+		return nil, fmt.Errorf("error creating client for the token: %w", err)
+	}
+
+	account, err := tokenClient.Users.ReadCurrent(ctx)
+	if errors.Is(err, tfe.ErrUnauthorized) {
+		return map[string]interface{}{
+			"valid": false,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the account of the token: %w", err)
+	}
+
+	return map[string]interface{}{
+		"valid":              true,
+		"user_id":            account.ID,
+		"username":           account.Username,
+		"is_service_account": account.IsServiceAccount,
+	}, nil
+}
+
+// lookupUpstreamToken reads the token from Terraform Cloud / Enterprise by ID.
+func (b *tfBackend) lookupUpstreamToken(ctx context.Context, s logical.Storage, tokenID string, credentialType string) (map[string]interface{}, error) {
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	upstream := map[string]interface{}{
+		"exists": true,
+	}
+
+	var createdAt, expiredAt, lastUsedAt time.Time
+	var createdBy *tfe.CreatedByChoice
+	if credentialType == teamCredentialType {
+		var token *tfe.TeamToken
+		token, err = client.TeamTokens.ReadByID(ctx, tokenID)
+		if token != nil {
+			createdAt, expiredAt, lastUsedAt, createdBy = token.CreatedAt, token.ExpiredAt, token.LastUsedAt, token.CreatedBy
+			if token.Description != nil {
+				upstream["description"] = *token.Description
+			}
+			if token.Team != nil {
+				upstream["team_id"] = token.Team.ID
+			}
+		}
+	} else {
+		var token *tfe.UserToken
+		token, err = client.UserTokens.Read(ctx, tokenID)
+		if token != nil {
+			createdAt, expiredAt, lastUsedAt, createdBy = token.CreatedAt, token.ExpiredAt, token.LastUsedAt, token.CreatedBy
+			upstream["description"] = token.Description
+		}
+	}
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		return map[string]interface{}{
+			"exists": false,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading token %q: %w", tokenID, err)
+	}
+
+	for key, t := range map[string]time.Time{
+		"created_at":   createdAt,
+		"expired_at":   expiredAt,
+		"last_used_at": lastUsedAt,
+	} {
+		if !t.IsZero() {
+			upstream[key] = t.Format(time.RFC3339)
+		}
+	}
+
+	if createdBy != nil {
+		switch {
+		case createdBy.User != nil:
+			upstream["created_by"] = createdBy.User.ID
+		case createdBy.Team != nil:
+			upstream["created_by"] = createdBy.Team.ID
+		case createdBy.Organization != nil:
+			upstream["created_by"] = createdBy.Organization.Name
+		}
+	}
+
+	return upstream, nil
+}
+
+const pathLookupHelpSyn = `Look up whether a Terraform Cloud / Enterprise token was issued by this mount.`
+
+const pathLookupHelpDesc = `
+This endpoint reports what is known about a token, given either the token
+itself or its ID. The token is never returned.
+
+For tokens issued by this mount, it reports the role, credential type, lease
+ID, entity ID and request ID of the request that issued it, when it was issued,
+and when it expired or was revoked. The mount keeps these records for 30 days
+after a token is revoked or expires, and only for tokens issued after records
+were introduced. Tokens held by organization and team_legacy roles are also
+recognized.
+
+It also reports the token as seen by Terraform Cloud / Enterprise: whether it
+still exists, when it was created, last used and expires, and, when the token
+itself is given, the account it belongs to.
+`
@@ -0,0 +1,207 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	expiredAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/account/details": func(w http.ResponseWriter, r *http.Request) {
+			switch r.Header.Get("Authorization") {
+			case "Bearer issued-token":
+				fmt.Fprint(w, `{"data":{"id":"user-123","type":"users","attributes":{"username":"ci-bot"}}}`)
+			case "Bearer org-token":
+				fmt.Fprint(w, `{"data":{"id":"user-org","type":"users","attributes":{"username":"api-org-acme","is-service-account":true}}}`)
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"errors":[{"status":"401","title":"unauthorized"}]}`)
+			}
+		},
+		"POST /api/v2/users/user-123/authentication-tokens": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"data":{"id":"at-issued","type":"authentication-tokens","attributes":{"token":"issued-token","expired-at":%q}}}`, expiredAt.Format(time.RFC3339))
+		},
+		"GET /api/v2/authentication-tokens/at-issued": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"data":{"id":"at-issued","type":"authentication-tokens","attributes":{"description":"vault","created-at":"2026-01-02T03:04:05Z","expired-at":%q}}}`, expiredAt.Format(time.RFC3339))
+		},
+		"DELETE /api/v2/authentication-tokens/at-issued": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	require.NoError(t, setRole(ctx, s, roleName, &terraformRoleEntry{
+		Name:           roleName,
+		UserID:         "user-123",
+		CredentialType: userCredentialType,
+	}))
+	require.NoError(t, setRole(ctx, s, "org", &terraformRoleEntry{
+		Name:           "org",
+		Organization:   "acme",
+		CredentialType: organizationCredentialType,
+		Token:          "org-token",
+		TokenID:        "at-org",
+	}))
+
+	credsResp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/" + roleName,
+		Storage:   s,
+		EntityID:  "entity-123",
+	})
+	require.NoError(t, err)
+	require.False(t, credsResp.IsError())
+
+	lookup := func(t *testing.T, data map[string]interface{}) map[string]interface{} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "lookup",
+			Storage:   s,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError(), resp.Error())
+		require.NotContains(t, fmt.Sprint(resp.Data), data["token"])
+		return resp.Data
+	}
+
+	t.Run("requires token or token_id", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "lookup",
+			Storage:   s,
+			Data:      map[string]interface{}{},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("issued token", func(t *testing.T) {
+		data := lookup(t, map[string]interface{}{"token": "issued-token"})
+		require.Equal(t, true, data["issued_by_mount"])
+		require.Equal(t, false, data["static"])
+		require.Equal(t, "at-issued", data["token_id"])
+		require.Equal(t, roleName, data["role"])
+		require.Equal(t, userCredentialType, data["credential_type"])
+		require.Equal(t, "entity-123", data["entity_id"])
+		require.Equal(t, expiredAt.Format(time.RFC3339), data["expired_at"])
+		require.Equal(t, "ci-bot", data["owner"].(map[string]interface{})["username"])
+
+		upstream := data["upstream"].(map[string]interface{})
+		require.Equal(t, true, upstream["exists"])
+		require.Equal(t, "vault", upstream["description"])
+		require.Equal(t, "2026-01-02T03:04:05Z", upstream["created_at"])
+	})
+
+	t.Run("revoked token by id", func(t *testing.T) {
+		credsResp.Secret.LeaseID = "terraform/creds/" + roleName + "/abc"
+		_, err := b.terraformTokenRevoke(ctx, &logical.Request{
+			Storage: s,
+			Secret:  credsResp.Secret,
+		}, nil)
+		require.NoError(t, err)
+
+		data := lookup(t, map[string]interface{}{"token_id": "at-issued"})
+		require.Equal(t, true, data["issued_by_mount"])
+		require.Equal(t, credsResp.Secret.LeaseID, data["lease_id"])
+		require.Contains(t, data, "revoked_at")
+	})
+
+	t.Run("static role token", func(t *testing.T) {
+		data := lookup(t, map[string]interface{}{"token": "org-token"})
+		require.Equal(t, true, data["issued_by_mount"])
+		require.Equal(t, true, data["static"])
+		require.Equal(t, "org", data["role"])
+		require.Equal(t, "at-org", data["token_id"])
+		require.Equal(t, true, data["owner"].(map[string]interface{})["is_service_account"])
+		require.Equal(t, false, data["upstream"].(map[string]interface{})["exists"])
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		data := lookup(t, map[string]interface{}{"token": "leaked-token"})
+		require.Equal(t, false, data["issued_by_mount"])
+		require.Equal(t, false, data["owner"].(map[string]interface{})["valid"])
+		require.NotContains(t, data, "token_id")
+	})
+}
+
+func TestPruneIssuedTokens(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	records := map[string]*issuedToken{
+		"live":    {TokenID: "at-live", IssuedAt: now},
+		"recent":  {TokenID: "at-recent", IssuedAt: now, RevokedAt: now.Add(-time.Hour)},
+		"revoked": {TokenID: "at-revoked", IssuedAt: now, RevokedAt: now.Add(-issuedTokenRetention - time.Hour)},
+		"expired": {TokenID: "at-expired", IssuedAt: now, ExpiredAt: now.Add(-issuedTokenRetention - time.Hour)},
+	}
+	for token, record := range records {
+		require.NoError(t, b.recordIssuedToken(ctx, s, record, token))
+	}
+
+	require.NoError(t, b.scanIssuedTokens(ctx, s, now))
+
+	for token, record := range records {
+		tokenID, err := b.issuedTokenIDForToken(ctx, s, token)
+		require.NoError(t, err)
+
+		stored, err := getIssuedToken(ctx, s, record.TokenID)
+		require.NoError(t, err)
+
+		if token == "revoked" || token == "expired" {
+			require.Empty(t, tokenID, token)
+			require.Nil(t, stored, token)
+		} else {
+			require.Equal(t, record.TokenID, tokenID, token)
+			require.NotNil(t, stored, token)
+		}
+	}
+}
+
+func TestScanIssuedTokensIsThrottled(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	stale := func(tokenID string) {
+		t.Helper()
+		require.NoError(t, putIssuedToken(ctx, s, &issuedToken{
+			TokenID:   tokenID,
+			IssuedAt:  now,
+			RevokedAt: now.Add(-issuedTokenRetention - time.Hour),
+		}))
+	}
+
+	stored := func(tokenID string) bool {
+		t.Helper()
+		record, err := getIssuedToken(ctx, s, tokenID)
+		require.NoError(t, err)
+		return record != nil
+	}
+
+	stale("at-first")
+	require.NoError(t, b.scanIssuedTokensIfDue(ctx, s, now))
+	require.False(t, stored("at-first"))
+
+	// the next runs of the periodic function within the interval skip the scan
+	stale("at-second")
+	require.NoError(t, b.scanIssuedTokensIfDue(ctx, s, now.Add(time.Minute)))
+	require.True(t, stored("at-second"))
+
+	require.NoError(t, b.scanIssuedTokensIfDue(ctx, s, now.Add(issuedTokenScanInterval)))
+	require.False(t, stored("at-second"))
+}
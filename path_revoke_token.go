@@ -0,0 +1,192 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRevokeToken(b *tfBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "revoke-token",
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTerraformCloud,
+			OperationVerb:   "revoke",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"token": {
+				Type:        framework.TypeString,
+				Description: "The leaked Terraform Cloud / Enterprise token to revoke. It is never returned.",
+				Required:    true,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathRevokeTokenWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "token",
+				},
+			},
+		},
+		HelpSynopsis:    pathRevokeTokenHelpSyn,
+		HelpDescription: pathRevokeTokenHelpDesc,
+	}
+}
+
+func (b *tfBackend) pathRevokeTokenWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	token := d.Get("token").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing token"), nil
+	}
+
+	tokenID, err := b.issuedTokenIDForToken(ctx, req.Storage, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenID != "" {
+		return b.revokeIssuedToken(ctx, req, tokenID)
+	}
+
+	roleEntry, err := b.staticRoleForToken(ctx, req.Storage, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleEntry != nil {
+		return b.rotateLeakedRoleToken(ctx, req, roleEntry)
+	}
+
+	return logical.ErrorResponse("token was not issued by this mount"), nil
+}
+
+// revokeIssuedToken revokes a user or team token upstream and marks its
+// record, so its lease can no longer be renewed and revoking the lease does
+// not fail on the missing token. The Vault lease itself is left to be revoked
+// by the caller.
+func (b *tfBackend) revokeIssuedToken(ctx context.Context, req *logical.Request, tokenID string) (*logical.Response, error) {
+	record, err := getIssuedToken(ctx, req.Storage, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("issued token %q has no record", tokenID)
+	}
+
+	ctx = withLogFields(ctx, "request_id", req.ID, "role", record.Role, "credential_type", record.CredentialType, "token_id", tokenID)
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	if record.CredentialType == teamCredentialType {
+		err = client.TeamTokens.DeleteByID(ctx, tokenID)
+	} else {
+		err = client.UserTokens.Delete(ctx, tokenID)
+	}
+	if err != nil && !errors.Is(err, tfe.ErrResourceNotFound) {
+		return nil, fmt.Errorf("error revoking token %q: %w", tokenID, err)
+	}
+
+	if record.RevokedAt.IsZero() {
+		record.RevokedAt = time.Now()
+	}
+	record.BreakGlass = true
+	if err := putIssuedToken(ctx, req.Storage, record); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Warn("revoked leaked token", logFields(ctx)...)
+	b.sendEvent(ctx, eventTypeTokenRevoke, tokenEventMetadata(record.Role, record.CredentialType, tokenID, record.ExpiredAt)...)
+
+	data := record.toResponseData()
+	data["revoked"] = true
+	data["rotated"] = false
+
+	resp := &logical.Response{Data: data}
+	if record.LeaseID != "" {
+		resp.AddWarning(fmt.Sprintf("the token no longer works and lease %q can no longer be renewed; revoke the lease to remove it from Vault", record.LeaseID))
+	} else {
+		resp.AddWarning(fmt.Sprintf("the token no longer works and its lease can no longer be renewed; its lease ID is not known yet, find it under sys/leases/lookup/%screds/%s and revoke it to remove it from Vault", req.MountPoint, record.Role))
+	}
+
+	return resp, nil
+}
+
+// rotateLeakedRoleToken replaces the token of an organization or team_legacy
+// role. Both are singletons, so creating the new token revokes the leaked one.
+func (b *tfBackend) rotateLeakedRoleToken(ctx context.Context, req *logical.Request, roleEntry *terraformRoleEntry) (*logical.Response, error) {
+	leakedTokenID := roleEntry.TokenID
+
+	lock := locksutil.LockForKey(b.roleLocks, roleEntry.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// rotate the role as stored now, in case it changed since it was matched
+	roleEntry, err := b.getRole(ctx, req.Storage, roleEntry.Name)
+	if err != nil {
+		return nil, err
+	}
+	if roleEntry == nil {
+		return logical.ErrorResponse("the role holding the token was deleted, its token may need to be revoked in Terraform Cloud / Enterprise"), nil
+	}
+
+	ctx = withLogFields(withRoleLogFields(ctx, req, roleEntry), "token_id", leakedTokenID)
+	err = b.storeRoleWithToken(ctx, req.Storage, roleEntry)
+	emitRotationMetric(roleEntry, rotationTriggerBreakGlass, err)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Logger().Warn("rotated role holding leaked token", append(logFields(ctx), "new_token_id", roleEntry.TokenID)...)
+	b.sendEvent(ctx, eventTypeRoleRotate, append(tokenEventMetadata(roleEntry.Name, roleEntry.CredentialType, roleEntry.TokenID, time.Time{}),
+		"trigger", rotationTriggerBreakGlass)...)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"token_id":        leakedTokenID,
+			"role":            roleEntry.Name,
+			"credential_type": roleEntry.CredentialType,
+			"static":          true,
+			"revoked":         true,
+			"rotated":         true,
+			"new_token_id":    roleEntry.TokenID,
+		},
+	}
+
+	if hashed := roleEntry.hashedTokenResponse(); hashed != nil {
+		resp.Data["new_token"] = hashed.Data["token"]
+		resp.Warnings = hashed.Warnings
+	}
+
+	return resp, nil
+}
+
+const pathRevokeTokenHelpSyn = `Revoke a leaked Terraform Cloud / Enterprise token by its value.`
+
+const pathRevokeTokenHelpDesc = `
+This endpoint is meant for emergencies, when a token issued by this mount has
+leaked and only its value is known. The token is matched against the salted
+hashes of the tokens issued by the mount and is never returned.
+
+A user or team token is revoked in Terraform Cloud / Enterprise right away, and
+its lease can no longer be renewed. Vault leases cannot be revoked by the
+backend, so the response includes the lease ID when known, and a warning to
+revoke the lease.
+
+If the token is held by an organization or team_legacy role, the role is
+rotated immediately, which revokes the leaked token.
+`
@@ -0,0 +1,128 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeToken(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	var lock sync.Mutex
+	deleted := make(map[string]bool)
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"DELETE /api/v2/authentication-tokens/at-team": func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			if deleted["at-team"] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			deleted["at-team"] = true
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"GET /api/v2/authentication-tokens/at-team": func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			if deleted["at-team"] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, `{"data":{"id":"at-team","type":"authentication-tokens","attributes":{}}}`)
+		},
+		"GET /api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+		},
+		"POST /api/v2/organizations/acme/authentication-token": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"data":{"id":"at-org-new","type":"authentication-tokens","attributes":{"token":"new-org-token"}}}`)
+		},
+	})
+
+	revokeToken := func(token string) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "revoke-token",
+			Storage:    s,
+			MountPoint: "terraform/",
+			Data:       map[string]interface{}{"token": token},
+		})
+	}
+
+	t.Run("unknown token", func(t *testing.T) {
+		resp, err := revokeToken("unknown-token")
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("issued team token", func(t *testing.T) {
+		require.NoError(t, setRole(ctx, s, "team", &terraformRoleEntry{
+			Name:           "team",
+			TeamID:         "team-123",
+			CredentialType: teamCredentialType,
+		}))
+		require.NoError(t, b.recordIssuedToken(ctx, s, &issuedToken{
+			TokenID:        "at-team",
+			Role:           "team",
+			CredentialType: teamCredentialType,
+			IssuedAt:       time.Now(),
+		}, "leaked-team-token"))
+
+		resp, err := revokeToken("leaked-team-token")
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+		require.Equal(t, "at-team", resp.Data["token_id"])
+		require.Equal(t, true, resp.Data["revoked"])
+		require.Equal(t, true, resp.Data["break_glass"])
+		require.Len(t, resp.Warnings, 1)
+		require.Contains(t, resp.Warnings[0], "sys/leases/lookup/terraform/creds/team")
+		require.True(t, deleted["at-team"])
+
+		secret := &logical.Secret{InternalData: map[string]interface{}{
+			"role":            "team",
+			"token_id":        "at-team",
+			"credential_type": teamCredentialType,
+		}}
+
+		// the lease can no longer be renewed
+		_, err = b.terraformTokenRenew(ctx, &logical.Request{Storage: s, Secret: secret}, nil)
+		require.ErrorContains(t, err, "no longer exists")
+
+		// but revoking it does not fail on the missing token
+		_, err = b.terraformTokenRevoke(ctx, &logical.Request{Storage: s, Secret: secret}, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("static role token", func(t *testing.T) {
+		require.NoError(t, setRole(ctx, s, "org", &terraformRoleEntry{
+			Name:           "org",
+			Organization:   "acme",
+			CredentialType: organizationCredentialType,
+			Token:          "leaked-org-token",
+			TokenID:        "at-org",
+		}))
+
+		resp, err := revokeToken("leaked-org-token")
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+		require.Equal(t, "at-org", resp.Data["token_id"])
+		require.Equal(t, true, resp.Data["rotated"])
+		require.Equal(t, "at-org-new", resp.Data["new_token_id"])
+
+		roleEntry, err := b.getRole(ctx, s, "org")
+		require.NoError(t, err)
+		require.Equal(t, "new-org-token", roleEntry.Token)
+		require.Equal(t, "at-org-new", roleEntry.TokenID)
+	})
+}
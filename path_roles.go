@@ -5,11 +5,15 @@ package tfc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/go-sockaddr"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -29,18 +33,82 @@ func credentialType_Values() []string {
 	}
 }
 
+// isStaticCredentialType reports whether roles of the credential type hold a
+// single stored token rather than issuing leased tokens.
+func isStaticCredentialType(credentialType string) bool {
+	return credentialType == organizationCredentialType || credentialType == teamLegacyCredentialType
+}
+
 // terraformRoleEntry is a Vault role construct that maps to TFC/TFE
 type terraformRoleEntry struct {
 	Name           string        `json:"name"`
 	Organization   string        `json:"organization,omitempty"`
 	TeamID         string        `json:"team_id,omitempty"`
+	TeamName       string        `json:"team_name,omitempty"`
 	UserID         string        `json:"user_id,omitempty"`
+	UserEmail      string        `json:"user_email,omitempty"`
+	Username       string        `json:"username,omitempty"`
+	UserOrg        string        `json:"user_organization,omitempty"`
 	Description    string        `json:"description,omitempty"`
 	TTL            time.Duration `json:"ttl"`
 	MaxTTL         time.Duration `json:"max_ttl"`
 	CredentialType string        `json:"credential_type,omitempty"`
 	Token          string        `json:"token,omitempty"`
 	TokenID        string        `json:"token_id,omitempty"`
+
+	// StoreTokenHash keeps only the salted TokenHash of the token in storage
+	StoreTokenHash bool   `json:"store_token_hash,omitempty"`
+	TokenHash      string `json:"token_hash,omitempty"`
+
+	DescriptionTemplate string `json:"description_template,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+
+	BoundEntityIDs []string                      `json:"bound_entity_ids,omitempty"`
+	BoundGroupIDs  []string                      `json:"bound_group_ids,omitempty"`
+	BoundCIDRs     []*sockaddr.SockAddrMarshaler `json:"bound_cidrs,omitempty"`
+
+	TeamTokenExpiry       string        `json:"team_token_expiry,omitempty"`
+	TeamTokenExpiryBuffer time.Duration `json:"team_token_expiry_buffer,omitempty"`
+
+	MaxActiveTokens    int           `json:"max_active_tokens,omitempty"`
+	IssuanceRateLimit  int           `json:"issuance_rate_limit,omitempty"`
+	IssuanceRateWindow time.Duration `json:"issuance_rate_window,omitempty"`
+
+	RotationPeriod   time.Duration `json:"rotation_period,omitempty"`
+	RotationSchedule string        `json:"rotation_schedule,omitempty"`
+	RotationWindow   time.Duration `json:"rotation_window,omitempty"`
+	LastRotated      time.Time     `json:"last_rotated,omitempty"`
+	NextRotation     time.Time     `json:"next_rotation,omitempty"`
+
+	SyncWorkspaceIDs     []string         `json:"sync_workspace_ids,omitempty"`
+	SyncVariableSetIDs   []string         `json:"sync_variable_set_ids,omitempty"`
+	SyncVariableKey      string           `json:"sync_variable_key,omitempty"`
+	SyncVariableCategory string           `json:"sync_variable_category,omitempty"`
+	SyncStatus           []roleSyncStatus `json:"sync_status,omitempty"`
+}
+
+// isStatic reports whether the role holds a single stored organization or
+// team_legacy token.
+func (r *terraformRoleEntry) isStatic() bool {
+	return isStaticCredentialType(r.CredentialType)
+}
+
+// inferCredentialType returns the credential type of the role, inferring it
+// from the role's fields for roles written before credential_type existed.
+// Such roles are migrated when the plugin is initialized.
+func (r *terraformRoleEntry) inferCredentialType() string {
+	switch {
+	case r.CredentialType != "":
+		return r.CredentialType
+	case r.UserID != "":
+		return userCredentialType
+	case r.TeamID != "":
+		return teamLegacyCredentialType
+	default:
+		return organizationCredentialType
+	}
 }
 
 func (r *terraformRoleEntry) toResponseData() map[string]interface{} {
@@ -52,24 +120,90 @@ func (r *terraformRoleEntry) toResponseData() map[string]interface{} {
 	if r.Description != "" {
 		respData["description"] = r.Description
 	}
+	if r.DescriptionTemplate != "" {
+		respData["description_template"] = r.DescriptionTemplate
+	}
+	if len(r.Metadata) > 0 {
+		respData["metadata"] = r.Metadata
+	}
+	if len(r.Tags) > 0 {
+		respData["tags"] = r.Tags
+	}
+	if r.StoreTokenHash {
+		respData["store_token_hash"] = true
+	}
+	if r.TeamTokenExpiry != "" {
+		respData["team_token_expiry"] = r.TeamTokenExpiry
+	}
+	if r.leaseBoundExpiry() {
+		respData["team_token_expiry_buffer"] = r.teamTokenExpiryBufferOrDefault().Seconds()
+	}
+	if r.MaxActiveTokens > 0 {
+		respData["max_active_tokens"] = r.MaxActiveTokens
+	}
+	if r.IssuanceRateLimit > 0 {
+		respData["issuance_rate_limit"] = r.IssuanceRateLimit
+		respData["issuance_rate_window"] = r.IssuanceRateWindow.Seconds()
+	}
+	if len(r.BoundEntityIDs) > 0 {
+		respData["bound_entity_ids"] = r.BoundEntityIDs
+	}
+	if len(r.BoundGroupIDs) > 0 {
+		respData["bound_group_ids"] = r.BoundGroupIDs
+	}
+	if len(r.BoundCIDRs) > 0 {
+		boundCIDRs := make([]string, 0, len(r.BoundCIDRs))
+		for _, cidr := range r.BoundCIDRs {
+			boundCIDRs = append(boundCIDRs, cidr.String())
+		}
+		respData["bound_cidrs"] = boundCIDRs
+	}
+	if r.CredentialType != "" {
+		respData["credential_type"] = r.CredentialType
+	}
 	if r.Organization != "" {
 		respData["organization"] = r.Organization
-		r.CredentialType = organizationCredentialType
+	}
+	if r.TeamName != "" {
+		respData["team_name"] = r.TeamName
 	}
 	if r.TeamID != "" {
 		respData["team_id"] = r.TeamID
-		// Default to legacy team credential type
-		if r.CredentialType == "" || r.CredentialType == teamLegacyCredentialType {
-			r.CredentialType = teamLegacyCredentialType
-			respData["credential_type"] = teamLegacyCredentialType
-		} else {
-			respData["credential_type"] = teamCredentialType
-		}
 	}
 	if r.UserID != "" {
 		respData["user_id"] = r.UserID
-		r.CredentialType = userCredentialType
-		respData["credential_type"] = userCredentialType
+	}
+	if r.UserEmail != "" {
+		respData["user_email"] = r.UserEmail
+	}
+	if r.Username != "" {
+		respData["username"] = r.Username
+	}
+	if r.UserOrg != "" {
+		respData["user_organization"] = r.UserOrg
+	}
+	if r.hasRotation() {
+		respData["rotation_period"] = r.RotationPeriod.Seconds()
+		respData["rotation_schedule"] = r.RotationSchedule
+		respData["rotation_window"] = r.RotationWindow.Seconds()
+	}
+	if !r.LastRotated.IsZero() {
+		respData["last_rotated"] = r.LastRotated
+	}
+	if !r.NextRotation.IsZero() {
+		respData["next_rotation"] = r.NextRotation
+	}
+	if r.hasSyncTargets() {
+		respData["sync_workspace_ids"] = r.SyncWorkspaceIDs
+		respData["sync_variable_set_ids"] = r.SyncVariableSetIDs
+		respData["sync_variable_key"] = r.SyncVariableKey
+		respData["sync_variable_category"] = r.SyncVariableCategory
+
+		syncStatus := make([]map[string]interface{}, 0, len(r.SyncStatus))
+		for _, status := range r.SyncStatus {
+			syncStatus = append(syncStatus, status.toResponseData())
+		}
+		respData["sync_status"] = syncStatus
 	}
 
 	return respData
@@ -95,6 +229,10 @@ func pathRole(b *tfBackend) []*framework.Path {
 					Type:        framework.TypeString,
 					Description: "Description of the token created by the role",
 				},
+				"description_template": {
+					Type:        framework.TypeString,
+					Description: "Template for the description of user and team tokens created by the role, e.g. \"vault-{{.RoleName}}-{{.DisplayName}}-{{.UniqueID}}\". Available fields are RoleName, Description, MountPath, LeaseIDPrefix, EntityID, DisplayName, RequestTime and UniqueID.",
+				},
 				"organization": {
 					Type:        framework.TypeString,
 					Description: "Name of the Terraform Cloud or Enterprise organization",
@@ -103,10 +241,26 @@ func pathRole(b *tfBackend) []*framework.Path {
 					Type:        framework.TypeString,
 					Description: "ID of the Terraform Cloud or Enterprise team under organization (e.g., settings/teams/team-xxxxxxxxxxxxx)",
 				},
+				"team_name": {
+					Type:        framework.TypeString,
+					Description: "Name of the Terraform Cloud or Enterprise team under organization. Resolved to a team_id when the role is written. Cannot be combined with team_id.",
+				},
 				"user_id": {
 					Type:        framework.TypeString,
 					Description: "ID of the Terraform Cloud or Enterprise user (e.g., user-xxxxxxxxxxxxxxxx)",
 				},
+				"user_email": {
+					Type:        framework.TypeString,
+					Description: "Email address of the Terraform Cloud or Enterprise user. Resolved to a user_id through the memberships of user_organization when the role is written.",
+				},
+				"username": {
+					Type:        framework.TypeString,
+					Description: "Username of the Terraform Cloud or Enterprise user. Resolved to a user_id through the memberships of user_organization when the role is written.",
+				},
+				"user_organization": {
+					Type:        framework.TypeString,
+					Description: "Name of the organization used to resolve user_email or username.",
+				},
 				"ttl": {
 					Type:        framework.TypeDurationSecond,
 					Description: "Default lease for generated credentials. If not set or set to 0, will use system default.",
@@ -117,7 +271,95 @@ func pathRole(b *tfBackend) []*framework.Path {
 				},
 				"credential_type": {
 					Type:        framework.TypeString,
-					Description: "Credential type to be used for the token. Can be either 'user', 'org', 'team', or 'team_legacy'(deprecated).",
+					Description: "Credential type to be used for the token. Can be either 'user', 'org', 'team', or 'team_legacy'(deprecated, see upgrade-role).",
+				},
+				"rotation_period": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How often the token of an organization or team_legacy role is rotated automatically. Cannot be combined with rotation_schedule. Set to 0 to disable.",
+				},
+				"rotation_schedule": {
+					Type:        framework.TypeString,
+					Description: "Cron-style schedule (e.g., \"0 2 * * SUN\") on which the token of an organization or team_legacy role is rotated automatically. Cannot be combined with rotation_period.",
+				},
+				"rotation_window": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long after a scheduled time a rotation may still happen. If the window is missed, the rotation waits for the next scheduled time. Requires rotation_schedule.",
+				},
+				"sync_workspace_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of workspaces (e.g., ws-xxxxxxxxxxxxxxxx) the token of an organization or team_legacy role is written to as a sensitive variable whenever it changes.",
+				},
+				"sync_variable_set_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of variable sets (e.g., varset-xxxxxxxxxxxxxxxx) the token of an organization or team_legacy role is written to as a sensitive variable whenever it changes.",
+				},
+				"sync_variable_key": {
+					Type:        framework.TypeString,
+					Description: "Key of the variable the token is written to at each sync target. Required with sync_workspace_ids or sync_variable_set_ids.",
+				},
+				"sync_variable_category": {
+					Type:        framework.TypeString,
+					Description: "Category of the variable the token is written to. Can be either 'terraform' or 'env'. Defaults to 'terraform'.",
+				},
+				"store_token_hash": {
+					Type:        framework.TypeBool,
+					Description: "Store only a salted hash of the organization or team_legacy token. The token is then only returned by the request that creates it.",
+				},
+				"token": {
+					Type:        framework.TypeString,
+					Description: "Existing organization or team_legacy token to adopt instead of creating a new one. The token must be the current API token of the organization or team.",
+					DisplayAttrs: &framework.DisplayAttributes{
+						Sensitive: true,
+					},
+				},
+				"token_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the token being adopted (e.g., at-xxxxxxxxxxxxxxxx). Required with token, and must match the current API token of the organization or team.",
+				},
+				"metadata": {
+					Type:        framework.TypeKVPairs,
+					Description: "Free-form key/value metadata for the role, e.g. owner=platform. Not sent to Terraform Cloud or Enterprise.",
+				},
+				"tags": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Tags for the role, which roles can be filtered by when listed.",
+				},
+				"team_token_expiry": {
+					Type:        framework.TypeString,
+					Description: "How the upstream expiry of team tokens is set. 'max_ttl' (the default) fixes it at the max_ttl of the role or the system. 'lease' sets it just beyond the lease TTL and replaces the token on renewal when needed.",
+				},
+				"team_token_expiry_buffer": {
+					Type:        framework.TypeDurationSecond,
+					Description: "With team_token_expiry = lease, how far beyond the lease TTL the token expires. Defaults to 5 minutes.",
+				},
+				"max_active_tokens": {
+					Type:        framework.TypeInt,
+					Description: "Maximum number of user or team tokens of the role whose leases have not been revoked. 0 means unlimited.",
+				},
+				"issuance_rate_limit": {
+					Type:        framework.TypeInt,
+					Description: "Maximum number of user or team tokens issued for the role within issuance_rate_window. 0 means unlimited.",
+				},
+				"issuance_rate_window": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Window issuance_rate_limit applies to, e.g. 1h.",
+				},
+				"bound_entity_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Vault entity IDs allowed to obtain credentials of the role or rotate it. Combined with bound_group_ids, a caller matching either is allowed.",
+				},
+				"bound_group_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Vault identity group IDs whose member entities are allowed to obtain credentials of the role or rotate it.",
+				},
+				"bound_cidrs": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "CIDR blocks the requests obtaining credentials of the role or rotating it must come from.",
+				},
+				"skip_validation": {
+					Type:        framework.TypeBool,
+					Description: "Skip checking the organization, team and user of the role against Terraform Cloud or Enterprise when writing the role.",
+					Default:     false,
 				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -143,6 +385,24 @@ func pathRole(b *tfBackend) []*framework.Path {
 				OperationSuffix: "roles",
 			},
 
+			Fields: map[string]*framework.FieldSchema{
+				"credential_type": {
+					Type:        framework.TypeString,
+					Description: "Only list roles with this credential type.",
+					Query:       true,
+				},
+				"organization": {
+					Type:        framework.TypeString,
+					Description: "Only list roles for this organization.",
+					Query:       true,
+				},
+				"tag": {
+					Type:        framework.TypeString,
+					Description: "Only list roles with this tag.",
+					Query:       true,
+				},
+			},
+
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.ListOperation: &framework.PathOperation{
 					Callback: b.pathRolesList,
@@ -161,7 +421,62 @@ func (b *tfBackend) pathRolesList(ctx context.Context, req *logical.Request, d *
 		return nil, err
 	}
 
-	return logical.ListResponse(entries), nil
+	credentialType := d.Get("credential_type").(string)
+	organization := d.Get("organization").(string)
+	tag := d.Get("tag").(string)
+
+	keys := make([]string, 0, len(entries))
+	keyInfo := make(map[string]interface{}, len(entries))
+	for _, name := range entries {
+		roleEntry, err := b.getRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if roleEntry == nil {
+			continue
+		}
+
+		if credentialType != "" && roleEntry.CredentialType != credentialType {
+			continue
+		}
+		if organization != "" && roleEntry.Organization != organization && roleEntry.UserOrg != organization {
+			continue
+		}
+		if tag != "" && !strutil.StrListContains(roleEntry.Tags, tag) {
+			continue
+		}
+
+		keys = append(keys, name)
+		keyInfo[name] = roleEntry.toListInfo()
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+// toListInfo returns the summary of the role included with each key when
+// roles are listed.
+func (r *terraformRoleEntry) toListInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"credential_type": r.CredentialType,
+		"ttl":             r.TTL.Seconds(),
+		"max_ttl":         r.MaxTTL.Seconds(),
+	}
+	if r.Organization != "" {
+		info["organization"] = r.Organization
+	}
+	if r.TeamID != "" {
+		info["team_id"] = r.TeamID
+	}
+	if r.UserID != "" {
+		info["user_id"] = r.UserID
+	}
+	if len(r.Tags) > 0 {
+		info["tags"] = r.Tags
+	}
+	if len(r.Metadata) > 0 {
+		info["metadata"] = r.Metadata
+	}
+	return info
 }
 
 func (b *tfBackend) pathRolesRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -174,24 +489,286 @@ func (b *tfBackend) pathRolesRead(ctx context.Context, req *logical.Request, d *
 		return nil, nil
 	}
 
+	data := entry.toResponseData()
+	if !entry.isStatic() {
+		usage, err := getRoleUsage(ctx, req.Storage, entry.Name)
+		if err != nil {
+			return nil, err
+		}
+		data["usage"] = entry.usageResponseData(usage, time.Now())
+	}
+
 	return &logical.Response{
-		Data: entry.toResponseData(),
+		Data: data,
 	}, nil
 }
 
+// adoptRoleToken stores an existing organization or team_legacy token on the
+// role instead of creating a new one, so tokens already in use keep working
+// when they are brought under Vault management.
+func (b *tfBackend) adoptRoleToken(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry, token string, tokenID string) (*logical.Response, error) {
+	if token == "" {
+		return logical.ErrorResponse("token cannot be empty"), nil
+	}
+
+	config, err := getConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("backend is not configured"), nil
+	}
+
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, err = validateAdoptedToken(ctx, client, config, roleEntry, token, tokenID)
+	if err != nil {
+		return logical.ErrorResponse("unable to adopt token: %s", err), nil
+	}
+
+	roleEntry.Token = token
+	roleEntry.TokenID = tokenID
+	roleEntry.LastRotated = time.Now()
+	roleEntry.NextRotation, err = roleEntry.nextRotationAfter(roleEntry.LastRotated)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.setRoleTokenHash(ctx, s, roleEntry); err != nil {
+		return nil, err
+	}
+
+	if err := setRole(ctx, s, roleEntry.Name, roleEntry); err != nil {
+		return nil, err
+	}
+
+	if err := b.syncRoleToken(ctx, s, roleEntry); err != nil {
+		b.Logger().Error("unable to sync role token", "role", roleEntry.Name, "error", err)
+	}
+
+	return nil, nil
+}
+
+// resolveRoleIDs fills in the team_id and user_id of a role from the
+// team_name, user_email or username given in its place. Names are resolved
+// on every write, so writing the role again picks up an ID that has gone
+// stale. It returns an error response when the role cannot be resolved.
+func (b *tfBackend) resolveRoleIDs(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry) (*logical.Response, error) {
+	needsTeam := roleEntry.TeamName != ""
+	needsUser := roleEntry.UserEmail != "" || roleEntry.Username != ""
+	if !needsTeam && !needsUser {
+		return nil, nil
+	}
+
+	if needsTeam && roleEntry.Organization == "" {
+		return logical.ErrorResponse("must provide an organization with team_name"), nil
+	}
+
+	if needsUser && roleEntry.UserOrg == "" {
+		return logical.ErrorResponse("must provide a user_organization with user_email or username"), nil
+	}
+
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if needsTeam {
+		teamID, err := resolveTeamID(ctx, client, roleEntry.Organization, roleEntry.TeamName)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		roleEntry.TeamID = teamID
+	}
+
+	if needsUser {
+		userID, err := resolveUserID(ctx, client, roleEntry.UserOrg, roleEntry.UserEmail, roleEntry.Username)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		roleEntry.UserID = userID
+	}
+
+	return nil, nil
+}
+
+// refreshRoleIDs re-resolves the team_id or user_id of a role that was
+// configured by name, for example after the team was deleted and recreated.
+// It reports whether an ID changed, in which case the role is persisted.
+func (b *tfBackend) refreshRoleIDs(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry) (bool, error) {
+	if !roleEntry.hasNamedTarget() {
+		return false, nil
+	}
+
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+
+	if roleEntry.TeamName != "" && roleEntry.Organization != "" {
+		teamID, err := resolveTeamID(ctx, client, roleEntry.Organization, roleEntry.TeamName)
+		if err != nil {
+			return false, err
+		}
+		if teamID != roleEntry.TeamID {
+			roleEntry.TeamID = teamID
+			changed = true
+		}
+	}
+
+	if (roleEntry.UserEmail != "" || roleEntry.Username != "") && roleEntry.UserOrg != "" {
+		userID, err := resolveUserID(ctx, client, roleEntry.UserOrg, roleEntry.UserEmail, roleEntry.Username)
+		if err != nil {
+			return false, err
+		}
+		if userID != roleEntry.UserID {
+			roleEntry.UserID = userID
+			changed = true
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	lock := locksutil.LockForKey(b.roleLocks, roleEntry.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// persist only the IDs, onto the role as it is stored now
+	stored, err := b.getRole(ctx, s, roleEntry.Name)
+	if err != nil {
+		return false, err
+	}
+
+	if stored == nil || !stored.sameNamedTarget(roleEntry) {
+		// the role was deleted or rewritten, and the write resolved its names
+		return true, nil
+	}
+
+	b.Logger().Info("resolved ID for role changed, updating role", "role", roleEntry.Name)
+	stored.TeamID = roleEntry.TeamID
+	stored.UserID = roleEntry.UserID
+	return true, setRole(ctx, s, stored.Name, stored)
+}
+
+// sameNamedTarget reports whether two versions of a role reference the same
+// team or user by name.
+func (r *terraformRoleEntry) sameNamedTarget(other *terraformRoleEntry) bool {
+	return r.Organization == other.Organization && r.TeamName == other.TeamName &&
+		r.UserOrg == other.UserOrg && r.UserEmail == other.UserEmail && r.Username == other.Username
+}
+
+// validate checks the settings of a role that can be checked without the
+// Terraform API. Role writes and imports share it, so an imported role is
+// held to the same rules as a written one.
+func (r *terraformRoleEntry) validate() error {
+	if r.UserID != "" && (r.Organization != "" || r.TeamID != "") {
+		return errors.New("cannot provide a user_id in combination with organization or team_id")
+	}
+
+	if r.UserID == "" && r.Organization == "" && r.TeamID == "" {
+		return errors.New("must provide an organization name, team id or name, or user id, email or username")
+	}
+
+	if !strutil.StrListContains(credentialType_Values(), r.CredentialType) {
+		return fmt.Errorf("unrecognized credential type: %s", r.CredentialType)
+	}
+
+	if r.MaxTTL != 0 && r.TTL > r.MaxTTL {
+		return errors.New("ttl cannot be greater than max_ttl")
+	}
+
+	if r.CredentialType == teamLegacyCredentialType && (r.Description != "" || r.TTL != 0 || r.MaxTTL != 0) {
+		return errors.New("cannot provide description, ttl, or max_ttl with credential_type = team_legacy, try credential_type = team.")
+	}
+
+	if r.DescriptionTemplate != "" {
+		if r.CredentialType != userCredentialType && r.CredentialType != teamCredentialType {
+			return errors.New("description_template is only supported with credential_type = user or team")
+		}
+		if _, err := parseDescriptionTemplate(r.DescriptionTemplate); err != nil {
+			return fmt.Errorf("invalid description_template: %w", err)
+		}
+	}
+
+	if err := r.validateRotation(); err != nil {
+		return err
+	}
+
+	if err := r.validateTeamTokenExpiry(); err != nil {
+		return err
+	}
+
+	if err := r.validateQuota(); err != nil {
+		return err
+	}
+
+	if r.SyncVariableCategory != "" && !strutil.StrListContains(syncVariableCategory_Values(), r.SyncVariableCategory) {
+		return fmt.Errorf("unrecognized sync_variable_category: %s", r.SyncVariableCategory)
+	}
+
+	if r.hasSyncTargets() {
+		if !r.isStatic() {
+			return errors.New("sync_workspace_ids and sync_variable_set_ids are only supported with credential_type = organization or team_legacy")
+		}
+		if r.SyncVariableKey == "" {
+			return errors.New("must provide sync_variable_key with sync_workspace_ids or sync_variable_set_ids")
+		}
+	}
+
+	return r.validateStoreTokenHash()
+}
+
+// needsNewToken reports whether writing an organization or team_legacy role
+// has to create a new token: when the role is new, when it now belongs to
+// another token owner, or when it holds no token it could return.
+func (r *terraformRoleEntry) needsNewToken(previous *terraformRoleEntry) bool {
+	switch {
+	case previous == nil:
+		return true
+	case r.CredentialType != previous.CredentialType || r.Organization != previous.Organization || r.TeamID != previous.TeamID:
+		return true
+	default:
+		// a role that stops storing only the hash of its token has no
+		// token left to read
+		return r.Token == "" && (!r.StoreTokenHash || r.TokenHash == "")
+	}
+}
+
+// hasNamedTarget reports whether the team or user of the role was given by
+// name rather than by ID.
+func (r *terraformRoleEntry) hasNamedTarget() bool {
+	return r.TeamName != "" || r.UserEmail != "" || r.Username != ""
+}
+
 func (b *tfBackend) pathRolesWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 	if name == "" {
 		return logical.ErrorResponse("missing role name"), nil
 	}
 
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
 	roleEntry, err := b.getRole(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
 
+	// keep the stored role to tell whether the write changes the token owner
+	var previous *terraformRoleEntry
 	if roleEntry == nil {
 		roleEntry = &terraformRoleEntry{}
+	} else {
+		stored := *roleEntry
+		previous = &stored
 	}
 
 	roleEntry.Name = name
@@ -225,16 +802,85 @@ func (b *tfBackend) pathRolesWrite(ctx context.Context, req *logical.Request, d
 		}
 	}
 
+	if teamName, ok := d.GetOk("team_name"); ok {
+		if _, ok := d.GetOk("team_id"); ok {
+			return logical.ErrorResponse("cannot provide both team_id and team_name"), nil
+		}
+		roleEntry.TeamName = teamName.(string)
+		roleEntry.TeamID = ""
+		if roleEntry.CredentialType == "" || roleEntry.CredentialType == teamLegacyCredentialType {
+			roleEntry.CredentialType = teamLegacyCredentialType
+		}
+	} else if _, ok := d.GetOk("team_id"); ok {
+		// an explicit team_id replaces any previously resolved team name
+		roleEntry.TeamName = ""
+	}
+
+	userEmail, hasUserEmail := d.GetOk("user_email")
+	username, hasUsername := d.GetOk("username")
+	if hasUserEmail || hasUsername {
+		if hasUserEmail && hasUsername {
+			return logical.ErrorResponse("cannot provide both user_email and username"), nil
+		}
+		if _, ok := d.GetOk("user_id"); ok {
+			return logical.ErrorResponse("cannot provide user_id in combination with user_email or username"), nil
+		}
+		roleEntry.UserEmail = ""
+		roleEntry.Username = ""
+		if hasUserEmail {
+			roleEntry.UserEmail = userEmail.(string)
+		} else {
+			roleEntry.Username = username.(string)
+		}
+		roleEntry.UserID = ""
+		if roleEntry.CredentialType == "" {
+			roleEntry.CredentialType = userCredentialType
+		}
+	} else if _, ok := d.GetOk("user_id"); ok {
+		// an explicit user_id replaces any previously resolved user
+		roleEntry.UserEmail = ""
+		roleEntry.Username = ""
+	}
+
+	if userOrg, ok := d.GetOk("user_organization"); ok {
+		roleEntry.UserOrg = userOrg.(string)
+	}
+
 	if description, ok := d.GetOk("description"); ok {
 		roleEntry.Description = description.(string)
 	}
 
-	if roleEntry.UserID != "" && (roleEntry.Organization != "" || roleEntry.TeamID != "") {
-		return logical.ErrorResponse("cannot provide a user_id in combination with organization or team_id"), nil
+	if metadata, ok := d.GetOk("metadata"); ok {
+		roleEntry.Metadata = metadata.(map[string]string)
 	}
 
-	if roleEntry.UserID == "" && roleEntry.Organization == "" && roleEntry.TeamID == "" {
-		return logical.ErrorResponse("must provide an organization name, team id, or user id"), nil
+	if boundEntityIDs, ok := d.GetOk("bound_entity_ids"); ok {
+		roleEntry.BoundEntityIDs = strutil.RemoveDuplicates(boundEntityIDs.([]string), false)
+	}
+
+	if boundGroupIDs, ok := d.GetOk("bound_group_ids"); ok {
+		roleEntry.BoundGroupIDs = strutil.RemoveDuplicates(boundGroupIDs.([]string), false)
+	}
+
+	if boundCIDRs, ok := d.GetOk("bound_cidrs"); ok {
+		roleEntry.BoundCIDRs, err = parseutil.ParseAddrs(boundCIDRs.([]string))
+		if err != nil {
+			return logical.ErrorResponse("invalid bound_cidrs: %s", err), nil
+		}
+	}
+
+	if tags, ok := d.GetOk("tags"); ok {
+		roleEntry.Tags = strutil.RemoveDuplicates(tags.([]string), false)
+	}
+
+	if descriptionTemplate, ok := d.GetOk("description_template"); ok {
+		roleEntry.DescriptionTemplate = descriptionTemplate.(string)
+	}
+
+	ctx = withRoleLogFields(ctx, req, roleEntry)
+
+	if resp, err := b.resolveRoleIDs(ctx, req.Storage, roleEntry); resp != nil || err != nil {
+		return resp, err
 	}
 
 	if ttlRaw, ok := d.GetOk("ttl"); ok {
@@ -245,13 +891,87 @@ func (b *tfBackend) pathRolesWrite(ctx context.Context, req *logical.Request, d
 		roleEntry.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
 	}
 
-	if roleEntry.MaxTTL != 0 && roleEntry.TTL > roleEntry.MaxTTL {
-		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	rotationChanged := false
+	if rotationPeriodRaw, ok := d.GetOk("rotation_period"); ok {
+		roleEntry.RotationPeriod = time.Duration(rotationPeriodRaw.(int)) * time.Second
+		rotationChanged = true
+	}
+
+	if rotationSchedule, ok := d.GetOk("rotation_schedule"); ok {
+		roleEntry.RotationSchedule = rotationSchedule.(string)
+		rotationChanged = true
+	}
+
+	if rotationWindowRaw, ok := d.GetOk("rotation_window"); ok {
+		roleEntry.RotationWindow = time.Duration(rotationWindowRaw.(int)) * time.Second
+	}
+
+	if teamTokenExpiry, ok := d.GetOk("team_token_expiry"); ok {
+		roleEntry.TeamTokenExpiry = teamTokenExpiry.(string)
+	}
+
+	if teamTokenExpiryBuffer, ok := d.GetOk("team_token_expiry_buffer"); ok {
+		roleEntry.TeamTokenExpiryBuffer = time.Duration(teamTokenExpiryBuffer.(int)) * time.Second
+	}
+
+	if maxActiveTokens, ok := d.GetOk("max_active_tokens"); ok {
+		roleEntry.MaxActiveTokens = maxActiveTokens.(int)
+	}
+
+	if issuanceRateLimit, ok := d.GetOk("issuance_rate_limit"); ok {
+		roleEntry.IssuanceRateLimit = issuanceRateLimit.(int)
+	}
+
+	if issuanceRateWindow, ok := d.GetOk("issuance_rate_window"); ok {
+		roleEntry.IssuanceRateWindow = time.Duration(issuanceRateWindow.(int)) * time.Second
+	}
+
+	syncChanged := false
+	if workspaceIDs, ok := d.GetOk("sync_workspace_ids"); ok {
+		roleEntry.SyncWorkspaceIDs = workspaceIDs.([]string)
+		syncChanged = true
+	}
+
+	if variableSetIDs, ok := d.GetOk("sync_variable_set_ids"); ok {
+		roleEntry.SyncVariableSetIDs = variableSetIDs.([]string)
+		syncChanged = true
+	}
+
+	if key, ok := d.GetOk("sync_variable_key"); ok {
+		roleEntry.SyncVariableKey = key.(string)
+		syncChanged = true
+	}
+
+	if category, ok := d.GetOk("sync_variable_category"); ok {
+		roleEntry.SyncVariableCategory = category.(string)
+		syncChanged = true
 	}
 
-	if roleEntry.CredentialType == teamLegacyCredentialType {
-		if roleEntry.Description != "" || roleEntry.TTL != 0 || roleEntry.MaxTTL != 0 {
-			return logical.ErrorResponse("cannot provide description, ttl, or max_ttl with credential_type = team_legacy, try credential_type = team."), fmt.Errorf("test error")
+	if storeTokenHash, ok := d.GetOk("store_token_hash"); ok {
+		roleEntry.StoreTokenHash = storeTokenHash.(bool)
+	}
+
+	if err := roleEntry.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	switch {
+	case !roleEntry.hasRotation():
+		roleEntry.NextRotation = time.Time{}
+	case rotationChanged && !roleEntry.LastRotated.IsZero():
+		roleEntry.NextRotation, _ = roleEntry.nextRotationAfter(roleEntry.LastRotated)
+	case rotationChanged:
+		roleEntry.NextRotation, _ = roleEntry.nextRotationAfter(time.Now())
+	}
+
+	if !d.Get("skip_validation").(bool) {
+		client, err := b.getClient(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := validateRoleTargets(ctx, client, roleEntry); err != nil {
+			return logical.ErrorResponse("role validation failed: %s (set skip_validation=true to bypass)", err), nil
 		}
 	}
 
@@ -259,13 +979,39 @@ func (b *tfBackend) pathRolesWrite(ctx context.Context, req *logical.Request, d
 	// create the token now. User tokens will be created when credentials are
 	// read.
 	if roleEntry.CredentialType == organizationCredentialType || roleEntry.CredentialType == teamLegacyCredentialType {
-		token, err := b.createToken(ctx, req.Storage, roleEntry)
-		if err != nil {
+		if token, ok := d.GetOk("token"); ok {
+			return b.adoptRoleToken(ctx, req.Storage, roleEntry, token.(string), d.Get("token_id").(string))
+		}
+
+		if roleEntry.needsNewToken(previous) {
+			if err := b.storeRoleWithToken(ctx, req.Storage, roleEntry); err != nil {
+				return nil, err
+			}
+
+			return roleEntry.hashedTokenResponse(), nil
+		}
+
+		// creating a token revokes the one in use, so edits that keep the
+		// token owner only update the role
+		if err := b.setRoleTokenHash(ctx, req.Storage, roleEntry); err != nil {
 			return nil, err
 		}
 
-		roleEntry.Token = token.Token
-		roleEntry.TokenID = token.ID
+		if err := setRole(ctx, req.Storage, name, roleEntry); err != nil {
+			return nil, err
+		}
+
+		if syncChanged && roleEntry.Token != "" {
+			if err := b.syncRoleToken(ctx, req.Storage, roleEntry); err != nil {
+				b.Logger().Error("unable to sync role token", "role", roleEntry.Name, "error", err)
+			}
+		}
+
+		return roleEntry.hashedTokenResponse(), nil
+	}
+
+	if _, ok := d.GetOk("token"); ok {
+		return logical.ErrorResponse("token can only be adopted with credential_type = organization or team_legacy"), nil
 	}
 
 	if err := setRole(ctx, req.Storage, name, roleEntry); err != nil {
@@ -276,15 +1022,33 @@ func (b *tfBackend) pathRolesWrite(ctx context.Context, req *logical.Request, d
 }
 
 func (b *tfBackend) pathRolesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete(ctx, "role/"+d.Get("name").(string))
+	name := d.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	err := req.Storage.Delete(ctx, "role/"+name)
 	if err != nil {
 		return nil, fmt.Errorf("error deleting terraform role: %w", err)
 	}
 
+	if err := req.Storage.Delete(ctx, roleUsagePrefix+name); err != nil {
+		return nil, fmt.Errorf("error deleting terraform role usage: %w", err)
+	}
+
 	return nil, nil
 }
 
 func setRole(ctx context.Context, s logical.Storage, name string, roleEntry *terraformRoleEntry) error {
+	// roles with store_token_hash keep the token in memory only, so it can be
+	// synced and returned by the request that created it
+	if roleEntry.StoreTokenHash && roleEntry.Token != "" {
+		stored := *roleEntry
+		stored.Token = ""
+		roleEntry = &stored
+	}
+
 	entry, err := logical.StorageEntryJSON("role/"+name, roleEntry)
 	if err != nil {
 		return err
@@ -350,10 +1114,80 @@ also set an expiration timer on the terraform token (including the system max tt
 credential_type "organization" or "team_legacy" can only have one active token at a
 time. When a new token is created, the old token will be revoked. This is
 because Terraform Cloud/Enterprise does not support multiple active tokens for these
-types.
+types. These roles are static roles: their token is read from "static-creds/"
+and they can also be managed through "static-role/". Updating such a role only
+creates a new token when its organization, team_id or credential_type changes;
+use "rotate-role/" to replace the token otherwise.
+
+Teams and users can be referenced by name instead of ID. Set team_name together
+with organization in place of team_id, or user_email or username together with
+user_organization in place of user_id. The name is resolved to an ID whenever
+the role is written, and resolved again when credentials are requested if the
+stored ID is no longer found.
+
+The description of user and team tokens can be built from a description_template,
+so tokens listed in Terraform Cloud / Enterprise can be traced back to the Vault
+request that created them. The template is a Go template with the fields
+RoleName, Description, MountPath, LeaseIDPrefix, EntityID, DisplayName,
+RequestTime and UniqueID, plus the functions of Vault's username templates.
+
+When a role is written, its organization, team and user are checked against
+Terraform Cloud / Enterprise: they must exist, the team must belong to the
+organization, and the configured token must be allowed to manage their API
+tokens. Set skip_validation to true to store the role without these checks.
+
+The token of an organization or team_legacy role can be rotated automatically
+by setting either rotation_period or a cron-style rotation_schedule. With a
+rotation_schedule, rotation_window limits how late a scheduled rotation may
+happen. The role reports last_rotated and next_rotation. Failed rotations are
+logged and retried on the next run.
+
+Whenever the token of an organization or team_legacy role changes, it can be
+written as a sensitive variable named sync_variable_key to the workspaces in
+sync_workspace_ids and the variable sets in sync_variable_set_ids. The outcome
+for each target is reported in sync_status when the role is read.
+
+An organization or team_legacy token that is already in use can be brought
+under Vault management by writing the role with token and token_id set. The
+token ID must be the current API token of the organization or team, and the
+token must be usable and able to read it; it is stored as is instead of being
+replaced, and is rotated on the role's schedule or through "rotate-role/" from
+then on.
+
+Who may use a role can be narrowed with bound_entity_ids, bound_group_ids and
+bound_cidrs. With entity or group bounds, the caller's entity must be listed
+or belong to one of the groups; with bound_cidrs, the request must come from
+one of the CIDR blocks. The bounds apply to reading and renewing credentials
+of the role and to rotating it, and a denied request reports why.
+
+User and team roles can cap how many tokens they hand out. max_active_tokens
+limits the tokens whose leases have not been revoked, and issuance_rate_limit
+limits the tokens issued within issuance_rate_window. Requests over either
+limit fail with a quota error, and the current usage is reported under
+"usage" when the role is read. The count of active tokens is corrected about
+once an hour from the tokens the mount has on record, so leases that were
+force-revoked do not hold on to their slot.
+
+Team tokens normally expire upstream at the max_ttl of the role or the system,
+however short their lease. With team_token_expiry = lease, a team token
+expires team_token_expiry_buffer after its lease instead. Terraform Cloud /
+Enterprise cannot extend a token, so a renewal that would outlive the token
+replaces it with a new one, returned in the renewal response. The old token is
+left to expire on its own, at most team_token_expiry_buffer beyond the lease it
+was issued for, and is revoked along with the lease.
+
+With store_token_hash set, an organization or team_legacy role keeps only a
+salted hash of its token and the token ID in storage. The token is returned
+once, by the role write or rotation that created it, and is no longer
+returned by "static-creds/". A role rotated on a schedule must then sync its
+token to workspaces or variable sets.
 
 `
 
 	pathRoleListHelpSynopsis    = `List the existing roles in Terraform Cloud / Enterprise backend`
-	pathRoleListHelpDescription = `Roles will be listed by the role name.`
+	pathRoleListHelpDescription = `
+Roles will be listed by the role name, along with their credential type,
+organization, team or user ID, TTLs, tags and metadata. The list can be
+filtered by credential_type, organization or tag.
+`
 )
@@ -6,9 +6,12 @@ package tfc
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/require"
@@ -249,6 +252,417 @@ func TestUserRole(t *testing.T) {
 	})
 }
 
+func TestRoleNameResolutionValidation(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	cases := map[string]struct {
+		data     map[string]interface{}
+		expected string
+	}{
+		"team_name without organization": {
+			data: map[string]interface{}{
+				"team_name": "platform",
+			},
+			expected: "must provide an organization with team_name",
+		},
+		"team_name with team_id": {
+			data: map[string]interface{}{
+				"organization": "acme",
+				"team_name":    "platform",
+				"team_id":      "team-123",
+			},
+			expected: "cannot provide both team_id and team_name",
+		},
+		"user_email with username": {
+			data: map[string]interface{}{
+				"user_organization": "acme",
+				"user_email":        "jo@example.com",
+				"username":          "jo",
+			},
+			expected: "cannot provide both user_email and username",
+		},
+		"user_email with user_id": {
+			data: map[string]interface{}{
+				"user_organization": "acme",
+				"user_email":        "jo@example.com",
+				"user_id":           "user-123",
+			},
+			expected: "cannot provide user_id in combination with user_email or username",
+		},
+		"username without user_organization": {
+			data: map[string]interface{}{
+				"username": "jo",
+			},
+			expected: "must provide a user_organization with user_email or username",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := testTokenRoleCreate(t, b, s, roleName, tc.data)
+			require.NoError(t, err)
+			require.True(t, resp.IsError())
+			require.Contains(t, resp.Error().Error(), tc.expected)
+		})
+	}
+}
+
+func TestRoleNameResolution(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var requests int
+	userID := "user-jo"
+
+	// count the requests that reach the API beyond the ping
+	count := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests++
+			handler(w, r)
+		}
+	}
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/organizations/acme/organization-memberships": count(func(w http.ResponseWriter, r *http.Request) {
+			// the query for "jo" also matches "jo-smith", and the exact
+			// match is on the second page
+			if r.URL.Query().Get("page[number]") != "2" {
+				fmt.Fprint(w, `{"data":[{"id":"ou-1","type":"organization-memberships","relationships":{"user":{"data":{"id":"user-jo-smith","type":"users"}}}}],`+
+					`"included":[{"id":"user-jo-smith","type":"users","attributes":{"username":"jo-smith"}}],`+
+					`"meta":{"pagination":{"current-page":1,"next-page":2,"total-pages":2}}}`)
+				return
+			}
+			fmt.Fprintf(w, `{"data":[{"id":"ou-2","type":"organization-memberships","relationships":{"user":{"data":{"id":%[1]q,"type":"users"}}}}],`+
+				`"included":[{"id":%[1]q,"type":"users","attributes":{"username":"jo"}}],`+
+				`"meta":{"pagination":{"current-page":2,"total-pages":2}}}`, userID)
+		}),
+		"POST /api/v2/users/{user}/authentication-tokens": count(func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("user") != userID {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"data":{"id":"at-new","type":"authentication-tokens","attributes":{"token":"new-token"}}}`)
+		}),
+		"/": count(http.NotFound),
+	})
+
+	resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"user_organization": "acme",
+		"username":          "jo",
+		"skip_validation":   true,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	roleEntry, err := b.getRole(ctx, s, roleName)
+	require.NoError(t, err)
+	require.Equal(t, "user-jo", roleEntry.UserID)
+
+	t.Run("read does not call the API", func(t *testing.T) {
+		mu.Lock()
+		before := requests
+		mu.Unlock()
+
+		resp, err := testTokenRoleRead(t, b, s)
+		require.NoError(t, err)
+		require.Equal(t, "user-jo", resp.Data["user_id"])
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, before, requests)
+	})
+
+	t.Run("stale ID is resolved again on creds", func(t *testing.T) {
+		mu.Lock()
+		userID = "user-jo-recreated"
+		mu.Unlock()
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + roleName,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "new-token", resp.Data["token"])
+
+		roleEntry, err := b.getRole(ctx, s, roleName)
+		require.NoError(t, err)
+		require.Equal(t, "user-jo-recreated", roleEntry.UserID)
+	})
+}
+
+func TestRoleListWithInfo(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	roles := []*terraformRoleEntry{
+		{
+			Name:           "org",
+			Organization:   "acme",
+			CredentialType: organizationCredentialType,
+			Tags:           []string{"prod"},
+		},
+		{
+			Name:           "team",
+			TeamID:         "team-123",
+			CredentialType: teamCredentialType,
+			TTL:            time.Hour,
+			Tags:           []string{"ci", "prod"},
+			Metadata:       map[string]string{"owner": "platform"},
+		},
+		{
+			Name:           "user",
+			UserID:         "user-123",
+			CredentialType: userCredentialType,
+		},
+	}
+	for _, role := range roles {
+		require.NoError(t, setRole(ctx, s, role.Name, role))
+	}
+
+	list := func(t *testing.T, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "role/",
+			Storage:   s,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("all roles", func(t *testing.T) {
+		resp := list(t, nil)
+		require.Equal(t, []string{"org", "team", "user"}, resp.Data["keys"])
+
+		info := resp.Data["key_info"].(map[string]interface{})["team"].(map[string]interface{})
+		require.Equal(t, teamCredentialType, info["credential_type"])
+		require.Equal(t, "team-123", info["team_id"])
+		require.Equal(t, float64(3600), info["ttl"])
+		require.Equal(t, []string{"ci", "prod"}, info["tags"])
+		require.Equal(t, map[string]string{"owner": "platform"}, info["metadata"])
+	})
+
+	t.Run("filter by credential type", func(t *testing.T) {
+		resp := list(t, map[string]interface{}{"credential_type": userCredentialType})
+		require.Equal(t, []string{"user"}, resp.Data["keys"])
+	})
+
+	t.Run("filter by organization", func(t *testing.T) {
+		resp := list(t, map[string]interface{}{"organization": "acme"})
+		require.Equal(t, []string{"org"}, resp.Data["keys"])
+	})
+
+	t.Run("filter by tag", func(t *testing.T) {
+		resp := list(t, map[string]interface{}{"tag": "prod"})
+		require.Equal(t, []string{"org", "team"}, resp.Data["keys"])
+	})
+}
+
+func TestAcceptanceTeamNameRole(t *testing.T) {
+	if !runAcceptanceTests {
+		t.SkipNow()
+	}
+
+	b, s := getTestBackend(t)
+
+	organization := checkEnvVars(t, envVarTerraformOrganization)
+	teamID := checkEnvVars(t, envVarTerraformTeamID)
+	token := checkEnvVars(t, envVarTerraformToken)
+
+	err := testConfigCreate(t, b, s, map[string]interface{}{
+		"token": token,
+	})
+	require.NoError(t, err)
+
+	client, err := b.getClient(context.Background(), s)
+	require.NoError(t, err)
+
+	team, err := client.Teams.Read(context.Background(), teamID)
+	require.NoError(t, err)
+
+	resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"organization":    organization,
+		"team_name":       team.Name,
+		"credential_type": "team",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testTokenRoleRead(t, b, s)
+	require.NoError(t, err)
+	require.Equal(t, teamID, resp.Data["team_id"])
+	require.Equal(t, team.Name, resp.Data["team_name"])
+}
+
+func TestAcceptanceRoleValidation(t *testing.T) {
+	if !runAcceptanceTests {
+		t.SkipNow()
+	}
+
+	b, s := getTestBackend(t)
+
+	organization := checkEnvVars(t, envVarTerraformOrganization)
+	token := checkEnvVars(t, envVarTerraformToken)
+
+	err := testConfigCreate(t, b, s, map[string]interface{}{
+		"token": token,
+	})
+	require.NoError(t, err)
+
+	t.Run("unknown team - fail", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"organization":    organization,
+			"team_id":         "team-doesnotexist",
+			"credential_type": "team",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "role validation failed")
+	})
+
+	t.Run("unknown team with skip_validation - pass", func(t *testing.T) {
+		resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"organization":    organization,
+			"team_id":         "team-doesnotexist",
+			"credential_type": "team",
+			"skip_validation": true,
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+	})
+}
+
+func TestRoleValidationTeamOrganization(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	listStatus := http.StatusOK
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+		},
+		"/api/v2/teams/team-123": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"team-123","type":"teams","attributes":{"name":"ops"}}}`)
+		},
+		"/api/v2/organizations/acme/teams": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(listStatus)
+			if listStatus != http.StatusOK {
+				fmt.Fprint(w, `{"errors":[{"status":"401","title":"unauthorized"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"data":[],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`)
+		},
+	})
+
+	create := func(t *testing.T) *logical.Response {
+		t.Helper()
+		resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+			"organization":    "acme",
+			"team_id":         "team-123",
+			"credential_type": "team",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		return resp
+	}
+
+	t.Run("team of another organization - fail", func(t *testing.T) {
+		resp := create(t)
+		require.Contains(t, resp.Error().Error(), `team "team-123" does not belong to organization "acme"`)
+	})
+
+	t.Run("lookup error is not reported as membership - fail", func(t *testing.T) {
+		listStatus = http.StatusUnauthorized
+		resp := create(t)
+		require.NotContains(t, resp.Error().Error(), "does not belong")
+		require.Contains(t, resp.Error().Error(), "unauthorized")
+	})
+}
+
+func TestRoleUpdateKeepsToken(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	tokens := 0
+	createToken := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		tokens++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data":{"id":"at-%d","type":"authentication-tokens","attributes":{"token":"token-%d"}}}`, tokens, tokens)
+	}
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+		},
+		"/api/v2/teams/team-123": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"team-123","type":"teams","attributes":{"name":"ops"}}}`)
+		},
+		"POST /api/v2/organizations/acme/authentication-token": createToken,
+		"POST /api/v2/teams/team-123/authentication-token":     createToken,
+	})
+
+	update := func(t *testing.T, data map[string]interface{}) *terraformRoleEntry {
+		t.Helper()
+		data["skip_validation"] = true
+		resp, err := testTokenRoleCreate(t, b, s, roleName, data)
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		roleEntry, err := b.getRole(ctx, s, roleName)
+		require.NoError(t, err)
+		return roleEntry
+	}
+
+	roleEntry := update(t, map[string]interface{}{"organization": "acme"})
+	require.Equal(t, "at-1", roleEntry.TokenID)
+
+	t.Run("metadata only", func(t *testing.T) {
+		roleEntry := update(t, map[string]interface{}{
+			"metadata": map[string]string{"team": "platform"},
+			"tags":     []string{"ci"},
+		})
+		require.Equal(t, "at-1", roleEntry.TokenID)
+		require.Equal(t, "token-1", roleEntry.Token)
+		require.Equal(t, "platform", roleEntry.Metadata["team"])
+	})
+
+	t.Run("rotation policy", func(t *testing.T) {
+		roleEntry := update(t, map[string]interface{}{"rotation_period": "24h"})
+		require.Equal(t, "at-1", roleEntry.TokenID)
+		require.WithinDuration(t, roleEntry.LastRotated.Add(24*time.Hour), roleEntry.NextRotation, time.Second)
+	})
+
+	t.Run("store_token_hash", func(t *testing.T) {
+		roleEntry := update(t, map[string]interface{}{
+			"rotation_period":  0,
+			"store_token_hash": true,
+		})
+		require.Equal(t, "at-1", roleEntry.TokenID)
+		require.Empty(t, roleEntry.Token)
+		require.NotEmpty(t, roleEntry.TokenHash)
+
+		hash := roleEntry.TokenHash
+		roleEntry = update(t, map[string]interface{}{"description": "ci"})
+		require.Equal(t, "at-1", roleEntry.TokenID)
+		require.Equal(t, hash, roleEntry.TokenHash)
+	})
+
+	t.Run("token owner changed", func(t *testing.T) {
+		roleEntry := update(t, map[string]interface{}{
+			"team_id":          "team-123",
+			"store_token_hash": false,
+		})
+		require.Equal(t, "at-2", roleEntry.TokenID)
+		require.Equal(t, "token-2", roleEntry.Token)
+	})
+}
+
 // Utility function to create a role while, returning any response (including errors)
 func testTokenRoleCreate(t *testing.T, b *tfBackend, s logical.Storage, name string, d map[string]interface{}) (*logical.Response, error) {
 	t.Helper()
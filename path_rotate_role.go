@@ -5,8 +5,10 @@ package tfc
 
 import (
 	"context"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -48,6 +50,10 @@ func (b *tfBackend) pathRotateRole(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse("missing role name"), nil
 	}
 
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
 	roleEntry, err := b.getRole(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
@@ -57,6 +63,10 @@ func (b *tfBackend) pathRotateRole(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse("missing role entry"), nil
 	}
 
+	if resp, err := b.checkRoleAccess(req, roleEntry); resp != nil || err != nil {
+		return resp, err
+	}
+
 	if roleEntry.UserID != "" {
 		return logical.ErrorResponse("cannot rotate credentials for user roles"), nil
 	}
@@ -65,18 +75,17 @@ func (b *tfBackend) pathRotateRole(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse("cannot rotate credentials for credential_type = team token roles. Only works for credential_type = team_legacy."), nil
 	}
 
-	token, err := b.createToken(ctx, req.Storage, roleEntry)
+	ctx = withRoleLogFields(ctx, req, roleEntry)
+	err = b.storeRoleWithToken(ctx, req.Storage, roleEntry)
+	emitRotationMetric(roleEntry, rotationTriggerManual, err)
 	if err != nil {
 		return nil, err
 	}
 
-	roleEntry.Token = token.Token
-
-	if err := setRole(ctx, req.Storage, name, roleEntry); err != nil {
-		return nil, err
-	}
+	b.sendEvent(ctx, eventTypeRoleRotate, append(tokenEventMetadata(roleEntry.Name, roleEntry.CredentialType, roleEntry.TokenID, time.Time{}),
+		"trigger", rotationTriggerManual)...)
 
-	return nil, nil
+	return roleEntry.hashedTokenResponse(), nil
 }
 
 const pathRotateRoleHelpSyn = `
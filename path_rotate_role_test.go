@@ -52,7 +52,7 @@ func (e *testEnv) VerifyTokenChange(t *testing.T) {
 
 	rotateReq := &logical.Request{
 		Operation: logical.ReadOperation,
-		Path:      "creds/test-org-token",
+		Path:      "static-creds/test-org-token",
 		Storage:   e.Storage,
 	}
 	resp, err = e.Backend.HandleRequest(e.Context, rotateReq)
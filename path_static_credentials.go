@@ -0,0 +1,101 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathStaticCredentials(b *tfBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-creds/" + framework.GenericNameRegex("name"),
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTerraformCloud,
+			OperationVerb:   "read",
+			OperationSuffix: "static-credentials",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the static role",
+				Required:    true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathStaticCredentialsRead,
+			},
+		},
+
+		HelpSynopsis:    pathStaticCredentialsHelpSyn,
+		HelpDescription: pathStaticCredentialsHelpDesc,
+	}
+}
+
+func (b *tfBackend) pathStaticCredentialsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("name").(string)
+
+	roleEntry, err := b.getStaticRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleEntry == nil {
+		return logical.ErrorResponse("unknown static role: %s", roleName), nil
+	}
+
+	if resp, err := b.checkRoleAccess(req, roleEntry); resp != nil || err != nil {
+		return resp, err
+	}
+
+	data := map[string]interface{}{
+		"token_id":        roleEntry.TokenID,
+		"organization":    roleEntry.Organization,
+		"team_id":         roleEntry.TeamID,
+		"role":            roleEntry.Name,
+		"rotation_period": roleEntry.RotationPeriod.Seconds(),
+		"ttl":             float64(0),
+	}
+
+	if roleEntry.RotationSchedule != "" {
+		data["rotation_schedule"] = roleEntry.RotationSchedule
+	}
+
+	if !roleEntry.LastRotated.IsZero() {
+		data["last_vault_rotation"] = roleEntry.LastRotated
+	}
+
+	// the time left until the stored token is replaced
+	if ttl := time.Until(roleEntry.NextRotation).Truncate(time.Second); !roleEntry.NextRotation.IsZero() && ttl > 0 {
+		data["ttl"] = ttl.Seconds()
+	}
+
+	resp := &logical.Response{
+		Data: data,
+	}
+
+	if roleEntry.StoreTokenHash {
+		resp.AddWarning(fmt.Sprintf("role %q only stores a hash of its token, it is returned when the role is written or rotated", roleName))
+	} else {
+		data["token"] = roleEntry.Token
+	}
+
+	return resp, nil
+}
+
+const pathStaticCredentialsHelpSyn = `
+Read the stored Terraform Cloud or Enterprise API token of a static role.
+`
+
+const pathStaticCredentialsHelpDesc = `
+This path returns the organization or team_legacy API token stored on a
+static role, along with the time of the last rotation by Vault and the
+number of seconds until the next scheduled rotation. Reading this path does
+not create a new token; use "rotate-role/" to replace it.
+`
@@ -0,0 +1,75 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticCredentials(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	lastRotated := time.Now().Add(-time.Hour)
+	err := setRole(ctx, s, "static", &terraformRoleEntry{
+		Name:           "static",
+		Organization:   "acme",
+		CredentialType: organizationCredentialType,
+		Token:          "secret-token",
+		TokenID:        "at-123",
+		RotationPeriod: 2 * time.Hour,
+		LastRotated:    lastRotated,
+		NextRotation:   lastRotated.Add(2 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = setRole(ctx, s, "dynamic", &terraformRoleEntry{
+		Name:           "dynamic",
+		UserID:         "user-123",
+		CredentialType: userCredentialType,
+	})
+	require.NoError(t, err)
+
+	t.Run("read static creds", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-creds/static",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+		require.Nil(t, resp.Secret)
+		require.Equal(t, "secret-token", resp.Data["token"])
+		require.Equal(t, "at-123", resp.Data["token_id"])
+		require.Equal(t, float64(7200), resp.Data["rotation_period"])
+		require.True(t, lastRotated.Equal(resp.Data["last_vault_rotation"].(time.Time)))
+		require.InDelta(t, 3600, resp.Data["ttl"], 5)
+	})
+
+	t.Run("static creds of dynamic role - fail", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-creds/dynamic",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("dynamic creds of static role - fail", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/static",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "static-creds/static")
+	})
+}
@@ -0,0 +1,276 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathStaticRole(b *tfBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "static-role/" + framework.GenericNameRegex("name"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixTerraformCloud,
+				OperationSuffix: "static-role",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeLowerCaseString,
+					Description: "Name of the role",
+					Required:    true,
+				},
+				"organization": {
+					Type:        framework.TypeString,
+					Description: "Name of the Terraform Cloud or Enterprise organization",
+				},
+				"team_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the Terraform Cloud or Enterprise team under organization (e.g., settings/teams/team-xxxxxxxxxxxxx)",
+				},
+				"team_name": {
+					Type:        framework.TypeString,
+					Description: "Name of the Terraform Cloud or Enterprise team under organization. Resolved to a team_id when the role is written. Cannot be combined with team_id.",
+				},
+				"credential_type": {
+					Type:        framework.TypeString,
+					Description: "Credential type of the stored token. Can be either 'organization' or 'team_legacy'.",
+				},
+				"rotation_period": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How often the stored token is rotated automatically. Cannot be combined with rotation_schedule. Set to 0 to disable.",
+				},
+				"rotation_schedule": {
+					Type:        framework.TypeString,
+					Description: "Cron-style schedule (e.g., \"0 2 * * SUN\") on which the stored token is rotated automatically. Cannot be combined with rotation_period.",
+				},
+				"rotation_window": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long after a scheduled time a rotation may still happen. If the window is missed, the rotation waits for the next scheduled time. Requires rotation_schedule.",
+				},
+				"sync_workspace_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of workspaces the stored token is written to as a sensitive variable whenever it changes.",
+				},
+				"sync_variable_set_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of variable sets the stored token is written to as a sensitive variable whenever it changes.",
+				},
+				"sync_variable_key": {
+					Type:        framework.TypeString,
+					Description: "Key of the variable the token is written to at each sync target.",
+				},
+				"sync_variable_category": {
+					Type:        framework.TypeString,
+					Description: "Category of the variable the token is written to. Can be either 'terraform' or 'env'. Defaults to 'terraform'.",
+				},
+				"store_token_hash": {
+					Type:        framework.TypeBool,
+					Description: "Store only a salted hash of the stored token. The token is then only returned by the request that creates it.",
+				},
+				"token": {
+					Type:        framework.TypeString,
+					Description: "Existing organization or team_legacy token to adopt instead of creating a new one. The token must be the current API token of the organization or team.",
+					DisplayAttrs: &framework.DisplayAttributes{
+						Sensitive: true,
+					},
+				},
+				"token_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the token being adopted. Required with token, and must match the current API token of the organization or team.",
+				},
+				"metadata": {
+					Type:        framework.TypeKVPairs,
+					Description: "Free-form key/value metadata for the role, e.g. owner=platform. Not sent to Terraform Cloud or Enterprise.",
+				},
+				"tags": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Tags for the role, which roles can be filtered by when listed.",
+				},
+				"bound_entity_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Vault entity IDs allowed to read the stored token or rotate it. Combined with bound_group_ids, a caller matching either is allowed.",
+				},
+				"bound_group_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Vault identity group IDs whose member entities are allowed to read the stored token or rotate it.",
+				},
+				"bound_cidrs": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "CIDR blocks the requests reading the stored token or rotating it must come from.",
+				},
+				"skip_validation": {
+					Type:        framework.TypeBool,
+					Description: "Skip checking the organization and team of the role against Terraform Cloud or Enterprise when writing the role.",
+					Default:     false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathStaticRolesRead,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathStaticRolesWrite,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.pathStaticRolesDelete,
+				},
+			},
+			HelpSynopsis:    pathStaticRoleHelpSynopsis,
+			HelpDescription: pathStaticRoleHelpDescription,
+		},
+		{
+			Pattern: "static-role/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixTerraformCloud,
+				OperationVerb:   "list",
+				OperationSuffix: "static-roles",
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathStaticRolesList,
+				},
+			},
+
+			HelpSynopsis:    pathStaticRoleListHelpSynopsis,
+			HelpDescription: pathStaticRoleListHelpDescription,
+		},
+	}
+}
+
+// getStaticRole returns the named role, or nil if it does not exist or does
+// not hold a static token.
+func (b *tfBackend) getStaticRole(ctx context.Context, s logical.Storage, name string) (*terraformRoleEntry, error) {
+	roleEntry, err := b.getRole(ctx, s, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleEntry == nil || !roleEntry.isStatic() {
+		return nil, nil
+	}
+
+	return roleEntry, nil
+}
+
+func (b *tfBackend) pathStaticRolesList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, name := range entries {
+		roleEntry, err := b.getStaticRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if roleEntry != nil {
+			keys = append(keys, name)
+		}
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
+func (b *tfBackend) pathStaticRolesRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleEntry, err := b.getStaticRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	if roleEntry == nil {
+		return nil, nil
+	}
+
+	data := roleEntry.toResponseData()
+	delete(data, "ttl")
+	delete(data, "max_ttl")
+	data["credential_type"] = roleEntry.CredentialType
+	if !roleEntry.LastRotated.IsZero() {
+		data["last_vault_rotation"] = roleEntry.LastRotated
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *tfBackend) pathStaticRolesWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing role name"), nil
+	}
+
+	if credentialType, ok := d.GetOk("credential_type"); ok && !isStaticCredentialType(credentialType.(string)) {
+		return logical.ErrorResponse("static roles only support credential_type = organization or team_legacy, use role/%s for dynamic credentials", name), nil
+	}
+
+	roleEntry, err := b.getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleEntry != nil && !roleEntry.isStatic() {
+		return logical.ErrorResponse("role %q is not a static role", name), nil
+	}
+
+	return b.pathRolesWrite(ctx, req, d)
+}
+
+func (b *tfBackend) pathStaticRolesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	roleEntry, err := b.getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleEntry != nil && !roleEntry.isStatic() {
+		return logical.ErrorResponse("role %q is not a static role", name), nil
+	}
+
+	if err := req.Storage.Delete(ctx, "role/"+name); err != nil {
+		return nil, fmt.Errorf("error deleting terraform static role: %w", err)
+	}
+
+	return nil, nil
+}
+
+const (
+	pathStaticRoleHelpSynopsis    = `Manages static roles holding a single Terraform Cloud / Enterprise token.`
+	pathStaticRoleHelpDescription = `
+This path allows you to read and write static roles. A static role manages the
+single organization or team_legacy token that Terraform Cloud / Enterprise
+allows for an organization or team. The token is created when the role is
+written and stays the same until it is rotated, either through the
+"rotate-role/" endpoint or on the role's rotation_period or rotation_schedule.
+
+Static roles share their names with the roles managed through "role/", which
+continues to accept organization and team_legacy roles. The stored token is
+read from "static-creds/".
+
+With store_token_hash set, an organization or team_legacy role keeps only a
+salted hash of its token and the token ID in storage. The token is returned
+once, by the role write or rotation that created it, and is no longer
+returned by "static-creds/". A role rotated on a schedule must then sync its
+token to workspaces or variable sets.
+`
+
+	pathStaticRoleListHelpSynopsis    = `List the existing static roles in Terraform Cloud / Enterprise backend`
+	pathStaticRoleListHelpDescription = `Static roles will be listed by the role name.`
+)
@@ -0,0 +1,204 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRoles(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	err := setRole(ctx, s, "org", &terraformRoleEntry{
+		Name:           "org",
+		Organization:   "acme",
+		CredentialType: organizationCredentialType,
+		Token:          "secret-token",
+	})
+	require.NoError(t, err)
+
+	err = setRole(ctx, s, "legacy", &terraformRoleEntry{
+		Name:           "legacy",
+		Organization:   "acme",
+		TeamID:         "team-123",
+		CredentialType: teamLegacyCredentialType,
+	})
+	require.NoError(t, err)
+
+	err = setRole(ctx, s, "user", &terraformRoleEntry{
+		Name:           "user",
+		UserID:         "user-123",
+		CredentialType: userCredentialType,
+	})
+	require.NoError(t, err)
+
+	t.Run("list static roles", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "static-role/",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"org", "legacy"}, resp.Data["keys"])
+	})
+
+	t.Run("read static role", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-role/legacy",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, teamLegacyCredentialType, resp.Data["credential_type"])
+		require.Equal(t, "team-123", resp.Data["team_id"])
+		require.NotContains(t, resp.Data, "token")
+		require.NotContains(t, resp.Data, "ttl")
+	})
+
+	t.Run("read dynamic role", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-role/user",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+	})
+
+	t.Run("write dynamic credential type - fail", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "static-role/new",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"team_id":         "team-123",
+				"credential_type": teamCredentialType,
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("adopt token for dynamic role - fail", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/new",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"user_id":         "user-123",
+				"credential_type": userCredentialType,
+				"token":           "existing-token",
+				"skip_validation": true,
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("adopt token without config - fail", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "static-role/new",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"organization":    "acme",
+				"token":           "existing-token",
+				"skip_validation": true,
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+
+		roleEntry, err := b.getRole(ctx, s, "new")
+		require.NoError(t, err)
+		require.Nil(t, roleEntry)
+	})
+
+	t.Run("delete dynamic role - fail", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "static-role/user",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("delete static role", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "static-role/org",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		roleEntry, err := b.getRole(ctx, s, "org")
+		require.NoError(t, err)
+		require.Nil(t, roleEntry)
+	})
+}
+
+func TestStaticRoleAdoptToken(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/organizations/acme/authentication-token": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"at-org","type":"authentication-tokens","attributes":{}}}`)
+		},
+		"/api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+		},
+		"/api/v2/account/details": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"user-svc","type":"users","attributes":{"is-service-account":true}}}`)
+		},
+	})
+
+	adopt := func(t *testing.T, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		data["organization"] = "acme"
+		data["token"] = "existing-token"
+		data["skip_validation"] = true
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "static-role/org",
+			Storage:   s,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	// any other API token of the organization can read it as well, so the
+	// token ID is what ties the token to the organization
+	t.Run("without token_id - fail", func(t *testing.T) {
+		resp := adopt(t, map[string]interface{}{})
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "token_id is required")
+	})
+
+	t.Run("token_id of another token - fail", func(t *testing.T) {
+		resp := adopt(t, map[string]interface{}{"token_id": "at-other"})
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "not the current API token")
+	})
+
+	t.Run("current token", func(t *testing.T) {
+		resp := adopt(t, map[string]interface{}{"token_id": "at-org"})
+		require.False(t, resp.IsError())
+
+		roleEntry, err := b.getRole(ctx, s, "org")
+		require.NoError(t, err)
+		require.Equal(t, "existing-token", roleEntry.Token)
+		require.Equal(t, "at-org", roleEntry.TokenID)
+	})
+}
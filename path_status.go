@@ -0,0 +1,253 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	statusOperationCreds                 = "creds"
+	statusOperationRenew                 = "renew"
+	statusOperationRevoke                = "revoke"
+	statusOperationRotation              = "rotation"
+	statusOperationLegacyTokenRevocation = "legacy_token_revocation"
+	statusOperationSync                  = "sync"
+
+	// statusProbeTimeout bounds how long the status check waits for the API.
+	statusProbeTimeout = 10 * time.Second
+)
+
+// backendError is the most recent failure of an operation, kept in memory for
+// the status endpoint.
+type backendError struct {
+	Time    time.Time
+	Message string
+}
+
+// lastErrors records the most recent failure of each operation of the
+// backend. It is local to the node and reset when the plugin restarts.
+type lastErrors struct {
+	lock   sync.Mutex
+	errors map[string]backendError
+}
+
+func (e *lastErrors) record(operation string, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.errors == nil {
+		e.errors = make(map[string]backendError)
+	}
+	e.errors[operation] = backendError{
+		Time:    time.Now(),
+		Message: err.Error(),
+	}
+}
+
+func (e *lastErrors) responseData() map[string]interface{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	data := make(map[string]interface{}, len(e.errors))
+	for operation, lastError := range e.errors {
+		data[operation] = map[string]interface{}{
+			"time":  lastError.Time.Format(time.RFC3339),
+			"error": lastError.Message,
+		}
+	}
+	return data
+}
+
+// recordError keeps err as the last error of the operation for the status
+// endpoint.
+func (b *tfBackend) recordError(operation string, err error) {
+	if err != nil {
+		b.lastErrors.record(operation, err)
+	}
+}
+
+func pathStatus(b *tfBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "status",
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTerraformCloud,
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathStatusRead,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "status",
+				},
+			},
+		},
+		HelpSynopsis:    pathStatusHelpSyn,
+		HelpDescription: pathStatusHelpDesc,
+	}
+}
+
+func (b *tfBackend) pathStatusRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"configured":  config != nil,
+		"checked_at":  time.Now().Format(time.RFC3339),
+		"last_errors": b.lastErrors.responseData(),
+	}
+
+	walIDs, err := framework.ListWAL(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	data["pending_wal_entries"] = len(walIDs)
+
+	revocations, err := req.Storage.List(ctx, legacyTokenRevocationPrefix)
+	if err != nil {
+		return nil, err
+	}
+	data["pending_legacy_token_revocations"] = len(revocations)
+
+	if config == nil {
+		data["healthy"] = false
+		return &logical.Response{Data: data}, nil
+	}
+
+	data["address"] = config.Address
+	data["base_path"] = config.BasePath
+
+	healthy := b.probeAPI(ctx, config, data)
+	if healthy && config.TokenID != "" {
+		healthy = b.probeTokenExpiry(ctx, req.Storage, config.TokenID, data)
+	}
+	data["healthy"] = healthy
+
+	return &logical.Response{Data: data}, nil
+}
+
+// probeAPI reads the account of the configured token, and adds reachability,
+// latency, token validity and rate limit headroom to data. It reports whether
+// the API was reachable and accepted the token.
+func (b *tfBackend) probeAPI(ctx context.Context, config *tfConfig, data map[string]interface{}) bool {
+	ctx, cancel := context.WithTimeout(ctx, statusProbeTimeout)
+	defer cancel()
+
+	u, err := url.JoinPath(config.Address, config.BasePath, "account/details")
+	if err != nil {
+		data["reachable"] = false
+		data["error"] = fmt.Sprintf("invalid address: %s", err)
+		return false
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		data["reachable"] = false
+		data["error"] = err.Error()
+		return false
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+config.Token)
+	httpReq.Header.Set("Accept", "application/vnd.api+json")
+
+	start := time.Now()
+	httpResp, err := cleanhttp.DefaultClient().Do(httpReq)
+	latency := time.Since(start)
+	if err != nil {
+		data["reachable"] = false
+		data["error"] = err.Error()
+		return false
+	}
+	defer httpResp.Body.Close()
+
+	data["reachable"] = true
+	data["latency_ms"] = latency.Milliseconds()
+	data["status_code"] = httpResp.StatusCode
+
+	for key, header := range map[string]string{
+		"rate_limit":           "X-RateLimit-Limit",
+		"rate_limit_remaining": "X-RateLimit-Remaining",
+	} {
+		if v, err := strconv.Atoi(httpResp.Header.Get(header)); err == nil {
+			data[key] = v
+		}
+	}
+	if v, err := strconv.ParseFloat(httpResp.Header.Get("X-RateLimit-Reset"), 64); err == nil {
+		data["rate_limit_reset_seconds"] = v
+	}
+
+	switch {
+	case httpResp.StatusCode == http.StatusOK:
+		data["token_valid"] = true
+		return true
+	case httpResp.StatusCode == http.StatusUnauthorized:
+		data["token_valid"] = false
+		data["error"] = "the configured token was rejected"
+	case httpResp.StatusCode == http.StatusTooManyRequests:
+		data["error"] = "rate limited"
+	default:
+		data["error"] = fmt.Sprintf("unexpected response: %s", httpResp.Status)
+	}
+	return false
+}
+
+// probeTokenExpiry adds the expiry of the configured token to data, and
+// reports whether the token is still valid.
+func (b *tfBackend) probeTokenExpiry(ctx context.Context, s logical.Storage, tokenID string, data map[string]interface{}) bool {
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		data["error"] = err.Error()
+		return false
+	}
+
+	// the configured token can be a user, team or organization token, all of
+	// which are read through the same authentication-tokens endpoint
+	token, err := client.UserTokens.Read(ctx, tokenID)
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		data["error"] = fmt.Sprintf("token %q not found", tokenID)
+		return false
+	}
+	if err != nil {
+		data["error"] = fmt.Sprintf("error reading token %q: %s", tokenID, err)
+		return false
+	}
+
+	if token.ExpiredAt.IsZero() {
+		return true
+	}
+
+	data["token_expired_at"] = token.ExpiredAt.Format(time.RFC3339)
+	data["token_expires_in_seconds"] = int64(time.Until(token.ExpiredAt).Seconds())
+	return time.Now().Before(token.ExpiredAt)
+}
+
+const pathStatusHelpSyn = `Report the health of the Terraform Cloud / Enterprise backend.`
+
+const pathStatusHelpDesc = `
+This endpoint checks the configured address and token against the Terraform
+Cloud / Enterprise API and reports whether the API is reachable, the latency
+of the check, whether the token is accepted and the rate limit headroom
+returned by the API. If the config has a token_id, the expiry of the token is
+reported too.
+
+It also reports the number of pending WAL entries and scheduled legacy token
+revocations, and the last error of each operation of the backend on this
+node: creds, renew, revoke, rotation, legacy_token_revocation and sync.
+
+The response sets "healthy" to false when the backend is not configured, the
+API cannot be reached, the token is rejected or has expired, which makes it
+suitable for monitoring checks.
+`
@@ -0,0 +1,110 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	readStatus := func(t *testing.T) map[string]interface{} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "status",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+		return resp.Data
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		data := readStatus(t)
+		require.Equal(t, false, data["configured"])
+		require.Equal(t, false, data["healthy"])
+		require.Equal(t, 0, data["pending_wal_entries"])
+		require.Equal(t, 0, data["pending_legacy_token_revocations"])
+	})
+
+	expiredAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	server := newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/account/details": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-RateLimit-Limit", "30")
+			w.Header().Set("X-RateLimit-Remaining", "29")
+			w.Header().Set("X-RateLimit-Reset", "0.5")
+			fmt.Fprint(w, `{"data":{"id":"user-123","type":"users","attributes":{"username":"vault"}}}`)
+		},
+		"/api/v2/authentication-tokens/at-config": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"data":{"id":"at-config","type":"authentication-tokens","attributes":{"expired-at":%q}}}`, expiredAt.Format(time.RFC3339))
+		},
+	})
+
+	t.Run("healthy", func(t *testing.T) {
+		err := testConfigCreate(t, b, s, map[string]interface{}{
+			"token":    "test-token",
+			"token_id": "at-config",
+			"address":  server.URL,
+		})
+		require.NoError(t, err)
+
+		b.recordError(statusOperationRotation, errors.New("rotation failed"))
+
+		data := readStatus(t)
+		require.Equal(t, true, data["configured"])
+		require.Equal(t, true, data["healthy"])
+		require.Equal(t, true, data["reachable"])
+		require.Equal(t, true, data["token_valid"])
+		require.Equal(t, server.URL, data["address"])
+		require.Equal(t, 30, data["rate_limit"])
+		require.Equal(t, 29, data["rate_limit_remaining"])
+		require.Equal(t, 0.5, data["rate_limit_reset_seconds"])
+		require.Equal(t, expiredAt.Format(time.RFC3339), data["token_expired_at"])
+		require.Contains(t, data, "latency_ms")
+
+		lastErrors := data["last_errors"].(map[string]interface{})
+		require.Equal(t, "rotation failed", lastErrors[statusOperationRotation].(map[string]interface{})["error"])
+	})
+
+	t.Run("token rejected", func(t *testing.T) {
+		err := testConfigUpdate(t, b, s, map[string]interface{}{
+			"token":   "wrong-token",
+			"address": server.URL,
+		})
+		require.NoError(t, err)
+
+		data := readStatus(t)
+		require.Equal(t, false, data["healthy"])
+		require.Equal(t, true, data["reachable"])
+		require.Equal(t, false, data["token_valid"])
+		require.Contains(t, data["error"], "rejected")
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		err := testConfigUpdate(t, b, s, map[string]interface{}{
+			"token":   "test-token",
+			"address": "http://127.0.0.1:1",
+		})
+		require.NoError(t, err)
+
+		data := readStatus(t)
+		require.Equal(t, false, data["healthy"])
+		require.Equal(t, false, data["reachable"])
+		require.NotEmpty(t, data["error"])
+	})
+}
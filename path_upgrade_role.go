@@ -0,0 +1,486 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const legacyTokenRevocationPrefix = "legacy-token-revocation/"
+
+const (
+	legacyTokenKept      = "kept"
+	legacyTokenRevoked   = "revoked"
+	legacyTokenScheduled = "scheduled"
+)
+
+// legacyTokenRevocation is a team_legacy token left behind by an upgraded
+// role, revoked by the periodic function once RevokeAfter has passed.
+type legacyTokenRevocation struct {
+	RoleName    string    `json:"role_name"`
+	TeamID      string    `json:"team_id"`
+	TokenID     string    `json:"token_id"`
+	RevokeAfter time.Time `json:"revoke_after"`
+}
+
+// upgradeOptions holds the defaults applied to a team_legacy role when it is
+// upgraded to credential_type = team.
+type upgradeOptions struct {
+	TTL                 time.Duration
+	MaxTTL              time.Duration
+	Description         string
+	RevokeLegacyToken   bool
+	RevokeGracePeriod   time.Duration
+	DryRun              bool
+	descriptionProvided bool
+}
+
+func upgradeRoleFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"ttl": {
+			Type:        framework.TypeDurationSecond,
+			Description: "Default lease for team tokens issued by the upgraded role.",
+		},
+		"max_ttl": {
+			Type:        framework.TypeDurationSecond,
+			Description: "Maximum time for team tokens issued by the upgraded role.",
+		},
+		"description": {
+			Type:        framework.TypeString,
+			Description: "Description of team tokens issued by the upgraded role.",
+		},
+		"revoke_legacy_token": {
+			Type:        framework.TypeBool,
+			Description: "Revoke the team_legacy token stored on the role once revoke_grace_period has passed.",
+			Default:     false,
+		},
+		"revoke_grace_period": {
+			Type:        framework.TypeDurationSecond,
+			Description: "How long the team_legacy token stays valid after the upgrade when revoke_legacy_token is set. Defaults to revoking it right away.",
+		},
+		"dry_run": {
+			Type:        framework.TypeBool,
+			Description: "Report what the upgrade would change without changing anything.",
+			Default:     false,
+		},
+	}
+}
+
+func pathUpgradeRole(b *tfBackend) []*framework.Path {
+	roleFields := upgradeRoleFields()
+	roleFields["name"] = &framework.FieldSchema{
+		Type:        framework.TypeLowerCaseString,
+		Description: "Name of the team_legacy role to upgrade",
+		Required:    true,
+	}
+
+	rolesFields := upgradeRoleFields()
+	rolesFields["roles"] = &framework.FieldSchema{
+		Type:        framework.TypeCommaStringSlice,
+		Description: "Names of the team_legacy roles to upgrade. Defaults to all team_legacy roles.",
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "upgrade-role/" + framework.GenericNameRegex("name"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixTerraformCloud,
+				OperationVerb:   "upgrade",
+				OperationSuffix: "role",
+			},
+
+			Fields: roleFields,
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathUpgradeRole,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+			},
+
+			HelpSynopsis:    pathUpgradeRoleHelpSyn,
+			HelpDescription: pathUpgradeRoleHelpDesc,
+		},
+		{
+			Pattern: "upgrade-roles/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixTerraformCloud,
+				OperationVerb:   "upgrade",
+				OperationSuffix: "roles",
+			},
+
+			Fields: rolesFields,
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathUpgradeRoles,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+			},
+
+			HelpSynopsis:    pathUpgradeRolesHelpSyn,
+			HelpDescription: pathUpgradeRolesHelpDesc,
+		},
+	}
+}
+
+func getUpgradeOptions(d *framework.FieldData) (upgradeOptions, error) {
+	opts := upgradeOptions{
+		TTL:               time.Duration(d.Get("ttl").(int)) * time.Second,
+		MaxTTL:            time.Duration(d.Get("max_ttl").(int)) * time.Second,
+		RevokeLegacyToken: d.Get("revoke_legacy_token").(bool),
+		RevokeGracePeriod: time.Duration(d.Get("revoke_grace_period").(int)) * time.Second,
+		DryRun:            d.Get("dry_run").(bool),
+	}
+
+	if description, ok := d.GetOk("description"); ok {
+		opts.Description = description.(string)
+		opts.descriptionProvided = true
+	}
+
+	if opts.MaxTTL != 0 && opts.TTL > opts.MaxTTL {
+		return opts, errors.New("ttl cannot be greater than max_ttl")
+	}
+
+	if opts.RevokeGracePeriod > 0 && !opts.RevokeLegacyToken {
+		return opts, errors.New("revoke_grace_period requires revoke_legacy_token")
+	}
+
+	return opts, nil
+}
+
+func (b *tfBackend) pathUpgradeRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing role name"), nil
+	}
+
+	opts, err := getUpgradeOptions(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// a rotation must not replace the legacy token while the role forgets it
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	roleEntry, err := b.getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleEntry == nil {
+		return logical.ErrorResponse("missing role entry"), nil
+	}
+
+	if roleEntry.CredentialType != teamLegacyCredentialType {
+		return logical.ErrorResponse("role %q has credential_type = %s, only team_legacy roles can be upgraded", name, roleEntry.CredentialType), nil
+	}
+
+	changes, err := b.upgradeRole(ctx, req.Storage, roleEntry, opts, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: changes,
+	}, nil
+}
+
+func (b *tfBackend) pathUpgradeRoles(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	opts, err := getUpgradeOptions(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	names := d.Get("roles").([]string)
+	explicit := len(names) > 0
+	if !explicit {
+		names, err = req.Storage.List(ctx, "role/")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	upgraded := make(map[string]interface{})
+	failed := make(map[string]interface{})
+	now := time.Now()
+
+	for _, name := range strutil.RemoveDuplicates(names, true) {
+		changes, err := b.upgradeNamedRole(ctx, req.Storage, name, opts, now)
+		if errors.Is(err, errNotTeamLegacyRole) {
+			// only report roles the caller asked for by name
+			if explicit {
+				failed[name] = err.Error()
+			}
+			continue
+		}
+		if err != nil {
+			// keep going, one failing role must not block the others
+			b.Logger().Error("unable to upgrade role", "role", name, "error", err)
+			failed[name] = err.Error()
+			continue
+		}
+		upgraded[name] = changes
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"dry_run":  opts.DryRun,
+			"upgraded": upgraded,
+			"failed":   failed,
+		},
+	}, nil
+}
+
+var errNotTeamLegacyRole = errors.New("not a team_legacy role")
+
+// upgradeNamedRole reads and upgrades a team_legacy role under its role lock,
+// so a rotation cannot replace the legacy token in between.
+func (b *tfBackend) upgradeNamedRole(ctx context.Context, s logical.Storage, name string, opts upgradeOptions, now time.Time) (map[string]interface{}, error) {
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	roleEntry, err := b.getRole(ctx, s, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleEntry == nil || roleEntry.CredentialType != teamLegacyCredentialType {
+		return nil, errNotTeamLegacyRole
+	}
+
+	return b.upgradeRole(ctx, s, roleEntry, opts, now)
+}
+
+// upgradeRole converts a team_legacy role to credential_type = team and
+// returns what changed. Callers hold the role lock. Settings that only apply to the single stored token
+// are dropped with the token, which is kept, revoked or scheduled for
+// revocation depending on opts.
+func (b *tfBackend) upgradeRole(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry, opts upgradeOptions, now time.Time) (map[string]interface{}, error) {
+	ctx = withRoleLogFields(ctx, nil, roleEntry)
+
+	changes := map[string]interface{}{
+		"role":                     roleEntry.Name,
+		"previous_credential_type": teamLegacyCredentialType,
+		"credential_type":          teamCredentialType,
+		"ttl":                      opts.TTL.Seconds(),
+		"max_ttl":                  opts.MaxTTL.Seconds(),
+		"description":              roleEntry.Description,
+	}
+	if opts.descriptionProvided {
+		changes["description"] = opts.Description
+	}
+
+	var cleared []string
+	if roleEntry.RotationPeriod > 0 {
+		cleared = append(cleared, "rotation_period")
+	}
+	if roleEntry.RotationSchedule != "" {
+		cleared = append(cleared, "rotation_schedule")
+	}
+	if roleEntry.RotationWindow > 0 {
+		cleared = append(cleared, "rotation_window")
+	}
+	if len(roleEntry.SyncWorkspaceIDs) > 0 {
+		cleared = append(cleared, "sync_workspace_ids")
+	}
+	if len(roleEntry.SyncVariableSetIDs) > 0 {
+		cleared = append(cleared, "sync_variable_set_ids")
+	}
+	if roleEntry.SyncVariableKey != "" {
+		cleared = append(cleared, "sync_variable_key")
+	}
+	if roleEntry.SyncVariableCategory != "" {
+		cleared = append(cleared, "sync_variable_category")
+	}
+	if len(cleared) > 0 {
+		changes["cleared"] = cleared
+	}
+
+	legacyTokenID := roleEntry.TokenID
+	legacyTokenStatus := legacyTokenKept
+	if legacyTokenID == "" {
+		legacyTokenStatus = ""
+	} else if opts.RevokeLegacyToken {
+		legacyTokenStatus = legacyTokenRevoked
+		if opts.RevokeGracePeriod > 0 {
+			legacyTokenStatus = legacyTokenScheduled
+			changes["legacy_token_revoke_after"] = now.Add(opts.RevokeGracePeriod)
+		}
+	}
+	if legacyTokenID != "" {
+		changes["legacy_token_id"] = legacyTokenID
+		changes["legacy_token"] = legacyTokenStatus
+	}
+
+	if opts.DryRun {
+		return changes, nil
+	}
+
+	upgraded := *roleEntry
+	upgraded.CredentialType = teamCredentialType
+	upgraded.TTL = opts.TTL
+	upgraded.MaxTTL = opts.MaxTTL
+	if opts.descriptionProvided {
+		upgraded.Description = opts.Description
+	}
+	upgraded.Token = ""
+	upgraded.TokenID = ""
+	upgraded.StoreTokenHash = false
+	upgraded.TokenHash = ""
+	upgraded.RotationPeriod = 0
+	upgraded.RotationSchedule = ""
+	upgraded.RotationWindow = 0
+	upgraded.LastRotated = time.Time{}
+	upgraded.NextRotation = time.Time{}
+	upgraded.SyncWorkspaceIDs = nil
+	upgraded.SyncVariableSetIDs = nil
+	upgraded.SyncVariableKey = ""
+	upgraded.SyncVariableCategory = ""
+	upgraded.SyncStatus = nil
+
+	if legacyTokenStatus == legacyTokenRevoked || legacyTokenStatus == legacyTokenScheduled {
+		// record the revocation before the role forgets the token, so the
+		// token is never left behind without a record of it
+		revocation := &legacyTokenRevocation{
+			RoleName:    roleEntry.Name,
+			TeamID:      roleEntry.TeamID,
+			TokenID:     legacyTokenID,
+			RevokeAfter: now.Add(opts.RevokeGracePeriod),
+		}
+		if err := putLegacyTokenRevocation(ctx, s, revocation); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := setRole(ctx, s, upgraded.Name, &upgraded); err != nil {
+		return nil, err
+	}
+
+	if legacyTokenStatus == legacyTokenRevoked {
+		if err := b.revokeLegacyToken(ctx, s, legacyTokenID); err != nil {
+			// the periodic function retries the revocation
+			b.Logger().Warn("unable to revoke legacy team token, will retry", "role", roleEntry.Name, "token_id", legacyTokenID, "error", err)
+			changes["legacy_token"] = legacyTokenScheduled
+			changes["legacy_token_revoke_after"] = now
+		}
+	}
+
+	return changes, nil
+}
+
+func putLegacyTokenRevocation(ctx context.Context, s logical.Storage, revocation *legacyTokenRevocation) error {
+	entry, err := logical.StorageEntryJSON(legacyTokenRevocationPrefix+revocation.TokenID, revocation)
+	if err != nil {
+		return err
+	}
+
+	if entry == nil {
+		return fmt.Errorf("failed to create storage entry for legacy token revocation")
+	}
+
+	return s.Put(ctx, entry)
+}
+
+func getLegacyTokenRevocation(ctx context.Context, s logical.Storage, tokenID string) (*legacyTokenRevocation, error) {
+	entry, err := s.Get(ctx, legacyTokenRevocationPrefix+tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var revocation legacyTokenRevocation
+	if err := entry.DecodeJSON(&revocation); err != nil {
+		return nil, err
+	}
+	return &revocation, nil
+}
+
+// revokeLegacyToken deletes a team_legacy token left behind by an upgraded
+// role and removes its revocation record. A token that no longer exists
+// counts as revoked.
+func (b *tfBackend) revokeLegacyToken(ctx context.Context, s logical.Storage, tokenID string) error {
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if err := client.TeamTokens.DeleteByID(ctx, tokenID); err != nil && !errors.Is(err, tfe.ErrResourceNotFound) {
+		return err
+	}
+
+	return s.Delete(ctx, legacyTokenRevocationPrefix+tokenID)
+}
+
+func (b *tfBackend) revokeDueLegacyTokens(ctx context.Context, s logical.Storage, now time.Time) error {
+	tokenIDs, err := s.List(ctx, legacyTokenRevocationPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, tokenID := range tokenIDs {
+		revocation, err := getLegacyTokenRevocation(ctx, s, tokenID)
+		if err != nil {
+			b.Logger().Error("unable to read legacy token revocation", "token_id", tokenID, "error", err)
+			b.recordError(statusOperationLegacyTokenRevocation, err)
+			continue
+		}
+
+		if revocation == nil || now.Before(revocation.RevokeAfter) {
+			continue
+		}
+
+		revokeCtx := withLogFields(ctx, "role", revocation.RoleName, "token_id", tokenID)
+		if err := b.revokeLegacyToken(revokeCtx, s, tokenID); err != nil {
+			b.Logger().Error("unable to revoke legacy team token", "role", revocation.RoleName, "token_id", tokenID, "error", err)
+			b.recordError(statusOperationLegacyTokenRevocation, fmt.Errorf("token %q: %w", tokenID, err))
+			continue
+		}
+		b.Logger().Info("revoked legacy team token", "role", revocation.RoleName, "token_id", tokenID)
+	}
+
+	return nil
+}
+
+const (
+	pathUpgradeRoleHelpSyn  = `Upgrade a team_legacy role to credential_type = team.`
+	pathUpgradeRoleHelpDesc = `
+This path converts a team_legacy role, which holds the single legacy token of
+a team, to a role issuing leased team tokens (credential_type = team). The
+ttl, max_ttl and description given are applied to the upgraded role.
+
+Settings that only apply to the stored token, such as rotation and sync
+targets, are cleared and reported under "cleared". The stored token is kept
+valid unless revoke_legacy_token is set, in which case it is revoked right away
+or once revoke_grace_period has passed. Set dry_run to see what would change
+without changing anything.
+`
+
+	pathUpgradeRolesHelpSyn  = `Upgrade team_legacy roles to credential_type = team in bulk.`
+	pathUpgradeRolesHelpDesc = `
+This path upgrades every team_legacy role, or the roles listed in "roles", the
+same way as "upgrade-role/<name>" does, applying the same options to each. The
+response reports the changes for each upgraded role under "upgraded" and the
+roles that could not be upgraded under "failed".
+`
+)
@@ -0,0 +1,232 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpgradeRole(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	legacyRole := func(name string, tokenID string) {
+		t.Helper()
+		err := setRole(ctx, s, name, &terraformRoleEntry{
+			Name:             name,
+			Organization:     "acme",
+			TeamID:           "team-123",
+			CredentialType:   teamLegacyCredentialType,
+			Token:            "legacy-token",
+			TokenID:          tokenID,
+			RotationPeriod:   time.Hour,
+			LastRotated:      time.Now(),
+			NextRotation:     time.Now().Add(time.Hour),
+			SyncWorkspaceIDs: []string{"ws-123"},
+			SyncVariableKey:  "tfe_token",
+		})
+		require.NoError(t, err)
+	}
+
+	upgrade := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		legacyRole("dry", "at-dry")
+
+		resp := upgrade("upgrade-role/dry", map[string]interface{}{
+			"ttl":                 "1h",
+			"revoke_legacy_token": true,
+			"dry_run":             true,
+		})
+		require.False(t, resp.IsError())
+		require.Equal(t, teamCredentialType, resp.Data["credential_type"])
+		require.Equal(t, legacyTokenRevoked, resp.Data["legacy_token"])
+		require.ElementsMatch(t, []string{"rotation_period", "sync_workspace_ids", "sync_variable_key"}, resp.Data["cleared"])
+
+		roleEntry, err := b.getRole(ctx, s, "dry")
+		require.NoError(t, err)
+		require.Equal(t, teamLegacyCredentialType, roleEntry.CredentialType)
+		require.Equal(t, "legacy-token", roleEntry.Token)
+	})
+
+	t.Run("keep legacy token", func(t *testing.T) {
+		legacyRole("keep", "at-keep")
+
+		resp := upgrade("upgrade-role/keep", map[string]interface{}{
+			"ttl":         "1h",
+			"max_ttl":     "2h",
+			"description": "upgraded",
+		})
+		require.False(t, resp.IsError())
+		require.Equal(t, legacyTokenKept, resp.Data["legacy_token"])
+		require.Equal(t, "at-keep", resp.Data["legacy_token_id"])
+
+		roleEntry, err := b.getRole(ctx, s, "keep")
+		require.NoError(t, err)
+		require.Equal(t, teamCredentialType, roleEntry.CredentialType)
+		require.Equal(t, time.Hour, roleEntry.TTL)
+		require.Equal(t, 2*time.Hour, roleEntry.MaxTTL)
+		require.Equal(t, "upgraded", roleEntry.Description)
+		require.Empty(t, roleEntry.Token)
+		require.Empty(t, roleEntry.TokenID)
+		require.False(t, roleEntry.hasRotation())
+		require.False(t, roleEntry.hasSyncTargets())
+
+		revocation, err := getLegacyTokenRevocation(ctx, s, "at-keep")
+		require.NoError(t, err)
+		require.Nil(t, revocation)
+	})
+
+	t.Run("schedule legacy token revocation", func(t *testing.T) {
+		legacyRole("grace", "at-grace")
+
+		resp := upgrade("upgrade-role/grace", map[string]interface{}{
+			"revoke_legacy_token": true,
+			"revoke_grace_period": "24h",
+		})
+		require.False(t, resp.IsError())
+		require.Equal(t, legacyTokenScheduled, resp.Data["legacy_token"])
+
+		revocation, err := getLegacyTokenRevocation(ctx, s, "at-grace")
+		require.NoError(t, err)
+		require.NotNil(t, revocation)
+		require.Equal(t, "grace", revocation.RoleName)
+		require.Equal(t, "team-123", revocation.TeamID)
+
+		// not due yet, so nothing is revoked
+		require.NoError(t, b.revokeDueLegacyTokens(ctx, s, time.Now()))
+		revocation, err = getLegacyTokenRevocation(ctx, s, "at-grace")
+		require.NoError(t, err)
+		require.NotNil(t, revocation)
+	})
+
+	t.Run("waits for a rotation in progress", func(t *testing.T) {
+		legacyRole("locked", "at-locked")
+
+		lock := locksutil.LockForKey(b.roleLocks, "locked")
+		lock.Lock()
+
+		done := make(chan *logical.Response)
+		go func() {
+			resp, _ := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "upgrade-roles",
+				Storage:   s,
+				Data: map[string]interface{}{
+					"roles":               "locked",
+					"revoke_legacy_token": true,
+					"revoke_grace_period": "24h",
+				},
+			})
+			done <- resp
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("upgrade did not wait for the role lock")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// the rotation replaces the token before releasing the lock
+		legacyRole("locked", "at-rotated")
+		lock.Unlock()
+
+		resp := <-done
+		require.NotNil(t, resp)
+		require.Contains(t, resp.Data["upgraded"], "locked")
+
+		revocation, err := getLegacyTokenRevocation(ctx, s, "at-rotated")
+		require.NoError(t, err)
+		require.NotNil(t, revocation)
+
+		revocation, err = getLegacyTokenRevocation(ctx, s, "at-locked")
+		require.NoError(t, err)
+		require.Nil(t, revocation)
+	})
+
+	t.Run("failed revocation is retried", func(t *testing.T) {
+		legacyRole("retry", "at-retry")
+
+		// the backend is not configured, so revoking right away fails
+		resp := upgrade("upgrade-role/retry", map[string]interface{}{
+			"revoke_legacy_token": true,
+		})
+		require.False(t, resp.IsError())
+		require.Equal(t, legacyTokenScheduled, resp.Data["legacy_token"])
+
+		revocation, err := getLegacyTokenRevocation(ctx, s, "at-retry")
+		require.NoError(t, err)
+		require.NotNil(t, revocation)
+
+		roleEntry, err := b.getRole(ctx, s, "retry")
+		require.NoError(t, err)
+		require.Equal(t, teamCredentialType, roleEntry.CredentialType)
+	})
+
+	t.Run("invalid options - fail", func(t *testing.T) {
+		legacyRole("invalid", "at-invalid")
+
+		resp := upgrade("upgrade-role/invalid", map[string]interface{}{
+			"ttl":     "2h",
+			"max_ttl": "1h",
+		})
+		require.True(t, resp.IsError())
+
+		resp = upgrade("upgrade-role/invalid", map[string]interface{}{
+			"revoke_grace_period": "1h",
+		})
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("non legacy role - fail", func(t *testing.T) {
+		resp := upgrade("upgrade-role/keep", map[string]interface{}{})
+		require.True(t, resp.IsError())
+
+		resp = upgrade("upgrade-role/missing", map[string]interface{}{})
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("bulk upgrade", func(t *testing.T) {
+		legacyRole("bulk-a", "")
+		legacyRole("bulk-b", "")
+
+		resp := upgrade("upgrade-roles", map[string]interface{}{
+			"roles": "bulk-a,keep",
+			"ttl":   "30m",
+		})
+		require.False(t, resp.IsError())
+		require.Contains(t, resp.Data["upgraded"], "bulk-a")
+		require.Contains(t, resp.Data["failed"], "keep")
+
+		resp = upgrade("upgrade-roles", map[string]interface{}{})
+		require.False(t, resp.IsError())
+		upgraded := resp.Data["upgraded"].(map[string]interface{})
+		require.Contains(t, upgraded, "bulk-b")
+		require.Contains(t, upgraded, "invalid")
+		require.NotContains(t, upgraded, "bulk-a")
+		require.Empty(t, resp.Data["failed"])
+
+		for _, name := range []string{"bulk-a", "bulk-b", "invalid"} {
+			roleEntry, err := b.getRole(ctx, s, name)
+			require.NoError(t, err)
+			require.Equal(t, teamCredentialType, roleEntry.CredentialType)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// hasAccessConstraints reports whether the role restricts who may obtain or
+// manage its credentials.
+func (r *terraformRoleEntry) hasAccessConstraints() bool {
+	return len(r.BoundEntityIDs) > 0 || len(r.BoundGroupIDs) > 0 || len(r.BoundCIDRs) > 0
+}
+
+// checkRoleAccess enforces the bound_entity_ids, bound_group_ids and
+// bound_cidrs of the role for the request. The caller must be one of the
+// bound entities or a member of one of the bound groups, if any are set, and
+// must connect from one of the bound CIDRs, if any are set. A denied request
+// gets an error response stating why along with logical.ErrPermissionDenied.
+func (b *tfBackend) checkRoleAccess(req *logical.Request, roleEntry *terraformRoleEntry) (*logical.Response, error) {
+	if !roleEntry.hasAccessConstraints() {
+		return nil, nil
+	}
+
+	var remoteAddr string
+	if req.Connection != nil {
+		remoteAddr = req.Connection.RemoteAddr
+	}
+
+	return b.checkCallerAccess(req, roleEntry, req.EntityID, remoteAddr)
+}
+
+// checkLeaseAccess enforces the bounds of the role on the renewal of a lease.
+// Vault renews leases without the entity or connection of the caller, so the
+// bounds are checked against the caller that obtained the lease, as recorded
+// in its internal data. Leases issued before the caller was recorded were
+// checked on issuance and are not checked again.
+func (b *tfBackend) checkLeaseAccess(req *logical.Request, roleEntry *terraformRoleEntry) (*logical.Response, error) {
+	if !roleEntry.hasAccessConstraints() {
+		return nil, nil
+	}
+
+	entityID, hasEntity := req.Secret.InternalData["entity_id"].(string)
+	remoteAddr, hasAddr := req.Secret.InternalData["remote_addr"].(string)
+	if !hasEntity && !hasAddr {
+		return nil, nil
+	}
+
+	return b.checkCallerAccess(req, roleEntry, entityID, remoteAddr)
+}
+
+func (b *tfBackend) checkCallerAccess(req *logical.Request, roleEntry *terraformRoleEntry, entityID string, remoteAddr string) (*logical.Response, error) {
+	if reason := b.roleAccessDeniedReason(roleEntry, entityID, remoteAddr); reason != "" {
+		b.Logger().Warn("denied access to role", "role", roleEntry.Name, "path", req.Path, "entity_id", entityID, "reason", reason)
+		return logical.ErrorResponse("permission denied for role %q: %s", roleEntry.Name, reason), logical.ErrPermissionDenied
+	}
+
+	return nil, nil
+}
+
+func (b *tfBackend) roleAccessDeniedReason(roleEntry *terraformRoleEntry, entityID string, remoteAddr string) string {
+	if len(roleEntry.BoundEntityIDs) > 0 || len(roleEntry.BoundGroupIDs) > 0 {
+		if entityID == "" {
+			return "the request is not associated with an entity"
+		}
+
+		if !strutil.StrListContains(roleEntry.BoundEntityIDs, entityID) {
+			member, err := b.entityInGroups(entityID, roleEntry.BoundGroupIDs)
+			if err != nil {
+				return fmt.Sprintf("unable to look up the groups of entity %q: %s", entityID, err)
+			}
+			if !member {
+				return fmt.Sprintf("entity %q is not in bound_entity_ids and not a member of any of bound_group_ids", entityID)
+			}
+		}
+	}
+
+	if len(roleEntry.BoundCIDRs) > 0 {
+		if remoteAddr == "" {
+			return "the source address of the request is unknown"
+		}
+
+		if !cidrutil.RemoteAddrIsOk(remoteAddr, roleEntry.BoundCIDRs) {
+			return fmt.Sprintf("source address %q is not in bound_cidrs", remoteAddr)
+		}
+	}
+
+	return ""
+}
+
+func (b *tfBackend) entityInGroups(entityID string, groupIDs []string) (bool, error) {
+	if len(groupIDs) == 0 {
+		return false, nil
+	}
+
+	groups, err := b.System().GroupsForEntity(entityID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, group := range groups {
+		if group != nil && strutil.StrListContains(groupIDs, group.ID) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
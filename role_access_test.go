@@ -0,0 +1,187 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleAccessConstraints(t *testing.T) {
+	ctx := context.Background()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = new(logical.InmemStorage)
+	config.Logger = hclog.NewNullLogger()
+	config.System = &logical.StaticSystemView{
+		GroupsVal: []*logical.Group{
+			{ID: "group-ops", Name: "ops"},
+		},
+	}
+
+	raw, err := Factory(ctx, config)
+	require.NoError(t, err)
+	b, s := raw.(*tfBackend), config.StorageView
+
+	roleEntry := &terraformRoleEntry{
+		Name:           "org",
+		Organization:   "acme",
+		CredentialType: organizationCredentialType,
+		Token:          "org-token",
+	}
+
+	read := func(t *testing.T, entityID string, remoteAddr string) (*logical.Response, error) {
+		t.Helper()
+		require.NoError(t, setRole(ctx, s, roleEntry.Name, roleEntry))
+
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-creds/org",
+			Storage:   s,
+			EntityID:  entityID,
+		}
+		if remoteAddr != "" {
+			req.Connection = &logical.Connection{RemoteAddr: remoteAddr}
+		}
+		return b.HandleRequest(ctx, req)
+	}
+
+	t.Run("invalid bound_cidrs - fail", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/user",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"user_id":         "user-123",
+				"bound_cidrs":     "not-a-cidr",
+				"skip_validation": true,
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "bound_cidrs")
+	})
+
+	t.Run("unbound role", func(t *testing.T) {
+		resp, err := read(t, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "org-token", resp.Data["token"])
+	})
+
+	roleEntry.BoundEntityIDs = []string{"entity-alice"}
+	roleEntry.BoundGroupIDs = []string{"group-ops"}
+
+	t.Run("bound entity", func(t *testing.T) {
+		resp, err := read(t, "entity-alice", "")
+		require.NoError(t, err)
+		require.Equal(t, "org-token", resp.Data["token"])
+	})
+
+	t.Run("bound group", func(t *testing.T) {
+		// every entity is a member of group-ops in this system view
+		resp, err := read(t, "entity-bob", "")
+		require.NoError(t, err)
+		require.Equal(t, "org-token", resp.Data["token"])
+	})
+
+	t.Run("no entity - fail", func(t *testing.T) {
+		resp, err := read(t, "", "")
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+		require.Contains(t, resp.Error().Error(), "not associated with an entity")
+	})
+
+	t.Run("entity not bound - fail", func(t *testing.T) {
+		roleEntry.BoundGroupIDs = []string{"group-dev"}
+
+		resp, err := read(t, "entity-bob", "")
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+		require.Contains(t, resp.Error().Error(), "entity-bob")
+	})
+
+	roleEntry.BoundEntityIDs = nil
+	roleEntry.BoundGroupIDs = nil
+	roleEntry.BoundCIDRs, err = parseutil.ParseAddrs("10.0.0.0/8")
+	require.NoError(t, err)
+
+	t.Run("bound cidr", func(t *testing.T) {
+		resp, err := read(t, "", "10.1.2.3")
+		require.NoError(t, err)
+		require.Equal(t, "org-token", resp.Data["token"])
+	})
+
+	t.Run("address outside bound cidr - fail", func(t *testing.T) {
+		resp, err := read(t, "", "192.168.1.1")
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+		require.Contains(t, resp.Error().Error(), "192.168.1.1")
+	})
+
+	t.Run("unknown address - fail", func(t *testing.T) {
+		resp, err := read(t, "", "")
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+		require.Contains(t, resp.Error().Error(), "source address")
+	})
+
+	t.Run("rotate-role is bound too", func(t *testing.T) {
+		require.NoError(t, setRole(ctx, s, roleEntry.Name, roleEntry))
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "rotate-role/org",
+			Storage:    s,
+			Connection: &logical.Connection{RemoteAddr: "192.168.1.1"},
+		})
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("renew checks the caller of the lease", func(t *testing.T) {
+		userRole := &terraformRoleEntry{
+			Name:           "user",
+			UserID:         "user-123",
+			CredentialType: userCredentialType,
+			BoundEntityIDs: []string{"entity-alice"},
+			BoundCIDRs:     roleEntry.BoundCIDRs,
+		}
+		require.NoError(t, setRole(ctx, s, userRole.Name, userRole))
+
+		// Vault renews leases without the entity or connection of the caller
+		renew := func(internalData map[string]interface{}) (*logical.Response, error) {
+			internalData["role"] = userRole.Name
+			req := logical.RenewRequest("creds/user", &logical.Secret{InternalData: internalData}, nil)
+			req.Storage = s
+			return b.terraformTokenRenew(ctx, req, nil)
+		}
+
+		resp, err := renew(map[string]interface{}{
+			"entity_id":   "entity-alice",
+			"remote_addr": "10.1.2.3",
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		// leases issued before the caller was recorded are not checked again
+		resp, err = renew(map[string]interface{}{})
+		require.NoError(t, err)
+		require.False(t, resp.IsError())
+
+		resp, err = renew(map[string]interface{}{
+			"entity_id":   "entity-bob",
+			"remote_addr": "10.1.2.3",
+		})
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+		require.Contains(t, resp.Error().Error(), "entity-bob")
+
+		resp, err = renew(map[string]interface{}{
+			"entity_id":   "entity-alice",
+			"remote_addr": "192.168.1.1",
+		})
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+		require.Contains(t, resp.Error().Error(), "192.168.1.1")
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	roleUsagePrefix = "role-usage/"
+
+	// roleUsageReconcileMinAge gives tokens reserved but not yet recorded
+	// time to be recorded before the usage of the role is recounted.
+	roleUsageReconcileMinAge = 5 * time.Minute
+)
+
+// roleUsage tracks the user and team tokens issued for a role: the number of
+// leases that have not been revoked yet, and the issuance times within the
+// role's issuance_rate_window.
+type roleUsage struct {
+	ActiveTokens int         `json:"active_tokens"`
+	Issued       []time.Time `json:"issued,omitempty"`
+	// ReservedAt is when a token was last reserved for the role
+	ReservedAt time.Time `json:"reserved_at,omitempty"`
+}
+
+// hasQuota reports whether the role limits how many tokens can be issued.
+func (r *terraformRoleEntry) hasQuota() bool {
+	return r.MaxActiveTokens > 0 || r.IssuanceRateLimit > 0
+}
+
+// validateQuota checks the quota settings of a role.
+func (r *terraformRoleEntry) validateQuota() error {
+	if r.MaxActiveTokens < 0 {
+		return errors.New("max_active_tokens cannot be negative")
+	}
+
+	if r.IssuanceRateLimit < 0 || r.IssuanceRateWindow < 0 {
+		return errors.New("issuance_rate_limit and issuance_rate_window cannot be negative")
+	}
+
+	if r.IssuanceRateLimit > 0 && r.IssuanceRateWindow == 0 {
+		return errors.New("issuance_rate_limit requires issuance_rate_window")
+	}
+
+	if r.IssuanceRateLimit == 0 && r.IssuanceRateWindow > 0 {
+		return errors.New("issuance_rate_window requires issuance_rate_limit")
+	}
+
+	if r.hasQuota() && r.CredentialType != userCredentialType && r.CredentialType != teamCredentialType {
+		return errors.New("max_active_tokens and issuance_rate_limit are only supported with credential_type = user or team")
+	}
+
+	return nil
+}
+
+// issuedSince returns the issuance times of the usage after t.
+func (u *roleUsage) issuedSince(t time.Time) []time.Time {
+	var issued []time.Time
+	for _, issuedAt := range u.Issued {
+		if issuedAt.After(t) {
+			issued = append(issued, issuedAt)
+		}
+	}
+	return issued
+}
+
+func getRoleUsage(ctx context.Context, s logical.Storage, name string) (*roleUsage, error) {
+	entry, err := s.Get(ctx, roleUsagePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := new(roleUsage)
+	if entry == nil {
+		return usage, nil
+	}
+
+	if err := entry.DecodeJSON(usage); err != nil {
+		return nil, fmt.Errorf("error reading usage of role %q: %w", name, err)
+	}
+	return usage, nil
+}
+
+func putRoleUsage(ctx context.Context, s logical.Storage, name string, usage *roleUsage) error {
+	entry, err := logical.StorageEntryJSON(roleUsagePrefix+name, usage)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+// reserveRoleToken counts a token about to be issued for the role against its
+// max_active_tokens and issuance_rate_limit. If either is reached, it returns
+// an error response stating which, along with a quota error.
+func (b *tfBackend) reserveRoleToken(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry, now time.Time) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.usageLocks, roleEntry.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	usage, err := getRoleUsage(ctx, s, roleEntry.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Issued = usage.issuedSince(now.Add(-roleEntry.IssuanceRateWindow))
+
+	if roleEntry.MaxActiveTokens > 0 && usage.ActiveTokens >= roleEntry.MaxActiveTokens {
+		b.Logger().Warn("role reached max_active_tokens", "role", roleEntry.Name, "active_tokens", usage.ActiveTokens)
+		return logical.ErrorResponse("role %q has reached max_active_tokens: %d of %d tokens are active, revoke a lease or wait for one to expire", roleEntry.Name, usage.ActiveTokens, roleEntry.MaxActiveTokens), logical.ErrLeaseCountQuotaExceeded
+	}
+
+	if roleEntry.IssuanceRateLimit > 0 && len(usage.Issued) >= roleEntry.IssuanceRateLimit {
+		retryAfter := usage.Issued[0].Add(roleEntry.IssuanceRateWindow).Sub(now).Round(time.Second)
+		b.Logger().Warn("role reached issuance_rate_limit", "role", roleEntry.Name, "issued", len(usage.Issued))
+		return logical.ErrorResponse("role %q has reached issuance_rate_limit: %d tokens issued in the last %s, retry in %s", roleEntry.Name, len(usage.Issued), roleEntry.IssuanceRateWindow, retryAfter), logical.ErrRateLimitQuotaExceeded
+	}
+
+	usage.ActiveTokens++
+	usage.ReservedAt = now
+	if roleEntry.IssuanceRateLimit > 0 {
+		usage.Issued = append(usage.Issued, now)
+	}
+
+	return nil, putRoleUsage(ctx, s, roleEntry.Name, usage)
+}
+
+// releaseRoleToken stops counting a token of the role as active, because its
+// lease was revoked or it could not be created. If issuedAt is set, the token
+// no longer counts against the issuance_rate_limit either.
+func (b *tfBackend) releaseRoleToken(ctx context.Context, s logical.Storage, name string, issuedAt time.Time) error {
+	lock := locksutil.LockForKey(b.usageLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	usage, err := getRoleUsage(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	if usage.ActiveTokens > 0 {
+		usage.ActiveTokens--
+	}
+
+	if !issuedAt.IsZero() {
+		for i, t := range usage.Issued {
+			if t.Equal(issuedAt) {
+				usage.Issued = append(usage.Issued[:i], usage.Issued[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return putRoleUsage(ctx, s, name, usage)
+}
+
+// reconcileRoleUsage corrects the active tokens of each role to the count
+// made from the records of the tokens it issued. Leases that were
+// force-revoked, or whose revocation failed or was lost, never release their
+// token, which would otherwise keep the role at max_active_tokens for good.
+// Roles that reserved a token within roleUsageReconcileMinAge are skipped,
+// since that token may not be recorded yet.
+func (b *tfBackend) reconcileRoleUsage(ctx context.Context, s logical.Storage, active map[string]int, now time.Time) error {
+	names, err := s.List(ctx, roleUsagePrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := b.reconcileUsageOfRole(ctx, s, name, active[name], now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *tfBackend) reconcileUsageOfRole(ctx context.Context, s logical.Storage, name string, active int, now time.Time) error {
+	lock := locksutil.LockForKey(b.usageLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	usage, err := getRoleUsage(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	if usage.ActiveTokens == active || now.Sub(usage.ReservedAt) < roleUsageReconcileMinAge {
+		return nil
+	}
+
+	b.Logger().Info("correcting active tokens of role", "role", name, "counted", usage.ActiveTokens, "active", active)
+	usage.ActiveTokens = active
+	return putRoleUsage(ctx, s, name, usage)
+}
+
+// usageResponseData returns the current usage of the role for role reads.
+func (r *terraformRoleEntry) usageResponseData(usage *roleUsage, now time.Time) map[string]interface{} {
+	data := map[string]interface{}{
+		"active_tokens": usage.ActiveTokens,
+	}
+	if r.IssuanceRateLimit > 0 {
+		data["issued_in_window"] = len(usage.issuedSince(now.Add(-r.IssuanceRateWindow)))
+	}
+	return data
+}
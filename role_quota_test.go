@@ -0,0 +1,218 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleQuotaValidation(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		data     map[string]interface{}
+		expected string
+	}{
+		"negative max_active_tokens": {
+			data:     map[string]interface{}{"user_id": "user-123", "max_active_tokens": -1},
+			expected: "cannot be negative",
+		},
+		"rate limit without window": {
+			data:     map[string]interface{}{"user_id": "user-123", "issuance_rate_limit": 5},
+			expected: "requires issuance_rate_window",
+		},
+		"window without rate limit": {
+			data:     map[string]interface{}{"user_id": "user-123", "issuance_rate_window": "1h"},
+			expected: "requires issuance_rate_limit",
+		},
+		"static role": {
+			data:     map[string]interface{}{"organization": "acme", "max_active_tokens": 5},
+			expected: "only supported with credential_type = user or team",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.data["skip_validation"] = true
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "role/quota",
+				Storage:   s,
+				Data:      tc.data,
+			})
+			require.NoError(t, err)
+			require.True(t, resp.IsError())
+			require.Contains(t, resp.Error().Error(), tc.expected)
+		})
+	}
+
+	t.Run("read usage", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/quota",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"user_id":              "user-123",
+				"max_active_tokens":    2,
+				"issuance_rate_limit":  5,
+				"issuance_rate_window": "1h",
+				"skip_validation":      true,
+			},
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		require.NoError(t, putRoleUsage(ctx, s, "quota", &roleUsage{
+			ActiveTokens: 1,
+			Issued:       []time.Time{time.Now().Add(-2 * time.Hour), time.Now().Add(-time.Minute)},
+		}))
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "role/quota",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, resp.Data["max_active_tokens"])
+		require.Equal(t, 5, resp.Data["issuance_rate_limit"])
+		require.Equal(t, map[string]interface{}{
+			"active_tokens":    1,
+			"issued_in_window": 1,
+		}, resp.Data["usage"])
+
+		_, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "role/quota",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+
+		entry, err := s.Get(ctx, roleUsagePrefix+"quota")
+		require.NoError(t, err)
+		require.Nil(t, entry)
+	})
+}
+
+func TestReserveRoleToken(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("max active tokens", func(t *testing.T) {
+		roleEntry := &terraformRoleEntry{
+			Name:            "active",
+			CredentialType:  userCredentialType,
+			MaxActiveTokens: 2,
+		}
+
+		for i := 0; i < 2; i++ {
+			resp, err := b.reserveRoleToken(ctx, s, roleEntry, now)
+			require.NoError(t, err)
+			require.Nil(t, resp)
+		}
+
+		resp, err := b.reserveRoleToken(ctx, s, roleEntry, now)
+		require.ErrorIs(t, err, logical.ErrLeaseCountQuotaExceeded)
+		require.Contains(t, resp.Error().Error(), "max_active_tokens")
+
+		// a revoked lease frees up a slot
+		require.NoError(t, b.releaseRoleToken(ctx, s, roleEntry.Name, time.Time{}))
+		resp, err = b.reserveRoleToken(ctx, s, roleEntry, now)
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		usage, err := getRoleUsage(ctx, s, roleEntry.Name)
+		require.NoError(t, err)
+		require.Equal(t, 2, usage.ActiveTokens)
+	})
+
+	t.Run("issuance rate limit", func(t *testing.T) {
+		roleEntry := &terraformRoleEntry{
+			Name:               "rate",
+			CredentialType:     teamCredentialType,
+			IssuanceRateLimit:  2,
+			IssuanceRateWindow: time.Hour,
+		}
+
+		for i := 0; i < 2; i++ {
+			resp, err := b.reserveRoleToken(ctx, s, roleEntry, now.Add(time.Duration(i)*time.Minute))
+			require.NoError(t, err)
+			require.Nil(t, resp)
+		}
+
+		resp, err := b.reserveRoleToken(ctx, s, roleEntry, now.Add(2*time.Minute))
+		require.ErrorIs(t, err, logical.ErrRateLimitQuotaExceeded)
+		require.Contains(t, resp.Error().Error(), "issuance_rate_limit")
+
+		// revoking does not reset the rate, but a failed creation does
+		require.NoError(t, b.releaseRoleToken(ctx, s, roleEntry.Name, time.Time{}))
+		resp, err = b.reserveRoleToken(ctx, s, roleEntry, now.Add(2*time.Minute))
+		require.ErrorIs(t, err, logical.ErrRateLimitQuotaExceeded)
+		require.NotNil(t, resp)
+
+		require.NoError(t, b.releaseRoleToken(ctx, s, roleEntry.Name, now))
+		resp, err = b.reserveRoleToken(ctx, s, roleEntry, now.Add(2*time.Minute))
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		// once the window has passed, tokens can be issued again
+		resp, err = b.reserveRoleToken(ctx, s, roleEntry, now.Add(time.Hour+time.Minute+time.Second))
+		require.NoError(t, err)
+		require.Nil(t, resp)
+	})
+
+	t.Run("release never goes negative", func(t *testing.T) {
+		require.NoError(t, b.releaseRoleToken(ctx, s, "unknown", time.Time{}))
+
+		usage, err := getRoleUsage(ctx, s, "unknown")
+		require.NoError(t, err)
+		require.Zero(t, usage.ActiveTokens)
+	})
+}
+
+func TestReconcileRoleUsage(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	records := []*issuedToken{
+		{TokenID: "at-active", Role: "quota", ExpiredAt: now.Add(time.Hour)},
+		{TokenID: "at-no-expiry", Role: "quota"},
+		{TokenID: "at-revoked", Role: "quota", RevokedAt: now.Add(-time.Hour)},
+		{TokenID: "at-expired", Role: "quota", ExpiredAt: now.Add(-time.Minute)},
+		{TokenID: "at-lease-expired", Role: "quota", LeaseExpiresAt: now.Add(-time.Minute)},
+		{TokenID: "at-replaced", Role: "quota", ReplacedBy: "at-active"},
+		{TokenID: "at-other", Role: "other"},
+	}
+	for _, record := range records {
+		require.NoError(t, putIssuedToken(ctx, s, record))
+	}
+
+	// a force-revoked lease never released its token
+	require.NoError(t, putRoleUsage(ctx, s, "quota", &roleUsage{
+		ActiveTokens: 5,
+		ReservedAt:   now.Add(-time.Hour),
+	}))
+	// a token reserved moments ago may not be recorded yet
+	require.NoError(t, putRoleUsage(ctx, s, "busy", &roleUsage{
+		ActiveTokens: 1,
+		ReservedAt:   now.Add(-time.Second),
+	}))
+
+	require.NoError(t, b.scanIssuedTokens(ctx, s, now))
+
+	usage, err := getRoleUsage(ctx, s, "quota")
+	require.NoError(t, err)
+	require.Equal(t, 2, usage.ActiveTokens)
+
+	usage, err = getRoleUsage(ctx, s, "busy")
+	require.NoError(t, err)
+	require.Equal(t, 1, usage.ActiveTokens)
+}
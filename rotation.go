@@ -0,0 +1,182 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/robfig/cron/v3"
+)
+
+// minRotationPeriod is the shortest rotation_period a role can have. Rotations
+// are driven by the periodic function, which runs about once a minute.
+const minRotationPeriod = time.Minute
+
+var rotationScheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// hasRotation reports whether the role has scheduled rotation configured.
+func (r *terraformRoleEntry) hasRotation() bool {
+	return r.RotationPeriod > 0 || r.RotationSchedule != ""
+}
+
+// nextRotationAfter returns the time of the first scheduled rotation after t,
+// or the zero time if the role does not rotate automatically.
+func (r *terraformRoleEntry) nextRotationAfter(t time.Time) (time.Time, error) {
+	if r.RotationPeriod > 0 {
+		return t.Add(r.RotationPeriod), nil
+	}
+
+	if r.RotationSchedule != "" {
+		schedule, err := rotationScheduleParser.Parse(r.RotationSchedule)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return schedule.Next(t), nil
+	}
+
+	return time.Time{}, nil
+}
+
+// validateRotation checks the rotation settings of a role.
+func (r *terraformRoleEntry) validateRotation() error {
+	if !r.hasRotation() {
+		if r.RotationWindow > 0 {
+			return fmt.Errorf("rotation_window requires rotation_schedule")
+		}
+		return nil
+	}
+
+	if r.CredentialType != organizationCredentialType && r.CredentialType != teamLegacyCredentialType {
+		return fmt.Errorf("rotation_period and rotation_schedule are only supported with credential_type = organization or team_legacy")
+	}
+
+	if r.RotationPeriod > 0 && r.RotationSchedule != "" {
+		return fmt.Errorf("cannot provide both rotation_period and rotation_schedule")
+	}
+
+	if r.RotationPeriod > 0 && r.RotationPeriod < minRotationPeriod {
+		return fmt.Errorf("rotation_period must be at least %s", minRotationPeriod)
+	}
+
+	if r.RotationWindow > 0 && r.RotationSchedule == "" {
+		return fmt.Errorf("rotation_window requires rotation_schedule")
+	}
+
+	if r.RotationSchedule != "" {
+		if _, err := rotationScheduleParser.Parse(r.RotationSchedule); err != nil {
+			return fmt.Errorf("invalid rotation_schedule: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// periodicFunc rotates the tokens of roles whose scheduled rotation is due,
+// revokes legacy tokens past their grace period and, less often, scans the
+// issued token records.
+func (b *tfBackend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	if !b.WriteSafeReplicationState() {
+		return nil
+	}
+
+	now := time.Now()
+	if err := b.rotateDueRoles(ctx, req.Storage, now); err != nil {
+		return err
+	}
+
+	if err := b.revokeDueLegacyTokens(ctx, req.Storage, now); err != nil {
+		return err
+	}
+
+	return b.scanIssuedTokensIfDue(ctx, req.Storage, now)
+}
+
+func (b *tfBackend) rotateDueRoles(ctx context.Context, s logical.Storage, now time.Time) error {
+	roles, err := s.List(ctx, "role/")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range roles {
+		if err := b.rotateDueRole(ctx, s, name, now); err != nil {
+			// keep going, one failing role must not block the others
+			b.Logger().Error("scheduled rotation failed", "role", name, "error", err)
+			b.recordError(statusOperationRotation, fmt.Errorf("role %q: %w", name, err))
+		}
+	}
+
+	return nil
+}
+
+// rotateDueRole rotates the role if its scheduled rotation is due. The role is
+// read under its lock, so a concurrent manual rotation or role write is not
+// undone.
+func (b *tfBackend) rotateDueRole(ctx context.Context, s logical.Storage, name string, now time.Time) error {
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	roleEntry, err := b.getRole(ctx, s, name)
+	if err != nil {
+		return fmt.Errorf("error reading role: %w", err)
+	}
+
+	if roleEntry == nil || !roleEntry.hasRotation() {
+		return nil
+	}
+
+	return b.rotateRoleIfDue(ctx, s, roleEntry, now)
+}
+
+func (b *tfBackend) rotateRoleIfDue(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry, now time.Time) error {
+	if roleEntry.NextRotation.IsZero() {
+		next, err := roleEntry.nextRotationAfter(roleEntry.lastRotatedOr(now))
+		if err != nil {
+			return err
+		}
+		roleEntry.NextRotation = next
+		return setRole(ctx, s, roleEntry.Name, roleEntry)
+	}
+
+	if now.Before(roleEntry.NextRotation) {
+		return nil
+	}
+
+	if roleEntry.RotationWindow > 0 && !now.Before(roleEntry.NextRotation.Add(roleEntry.RotationWindow)) {
+		// the window was missed, wait for the next scheduled rotation
+		next, err := roleEntry.nextRotationAfter(now)
+		if err != nil {
+			return err
+		}
+		b.Logger().Warn("missed rotation window, skipping to next scheduled rotation", "role", roleEntry.Name, "next_rotation", next)
+		roleEntry.NextRotation = next
+		return setRole(ctx, s, roleEntry.Name, roleEntry)
+	}
+
+	b.Logger().Info("rotating role token on schedule", "role", roleEntry.Name)
+	ctx = withRoleLogFields(ctx, nil, roleEntry)
+	err := b.storeRoleWithToken(ctx, s, roleEntry)
+	emitRotationMetric(roleEntry, rotationTriggerScheduled, err)
+	if err != nil {
+		return err
+	}
+
+	b.sendEvent(ctx, eventTypeRoleRotate, append(tokenEventMetadata(roleEntry.Name, roleEntry.CredentialType, roleEntry.TokenID, time.Time{}),
+		"trigger", rotationTriggerScheduled)...)
+
+	return nil
+}
+
+// lastRotatedOr returns the last rotation time of the role, or t if the role
+// has never been rotated.
+func (r *terraformRoleEntry) lastRotatedOr(t time.Time) time.Time {
+	if r.LastRotated.IsZero() {
+		return t
+	}
+	return r.LastRotated
+}
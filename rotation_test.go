@@ -0,0 +1,199 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleRotationValidation(t *testing.T) {
+	cases := map[string]struct {
+		role     terraformRoleEntry
+		expected string
+	}{
+		"no rotation": {
+			role: terraformRoleEntry{CredentialType: organizationCredentialType},
+		},
+		"period": {
+			role: terraformRoleEntry{CredentialType: organizationCredentialType, RotationPeriod: time.Hour},
+		},
+		"schedule with window": {
+			role: terraformRoleEntry{CredentialType: teamLegacyCredentialType, RotationSchedule: "0 2 * * SUN", RotationWindow: time.Hour},
+		},
+		"user role": {
+			role:     terraformRoleEntry{CredentialType: userCredentialType, RotationPeriod: time.Hour},
+			expected: "only supported with credential_type",
+		},
+		"period and schedule": {
+			role:     terraformRoleEntry{CredentialType: organizationCredentialType, RotationPeriod: time.Hour, RotationSchedule: "@daily"},
+			expected: "cannot provide both",
+		},
+		"short period": {
+			role:     terraformRoleEntry{CredentialType: organizationCredentialType, RotationPeriod: time.Second},
+			expected: "must be at least",
+		},
+		"window without schedule": {
+			role:     terraformRoleEntry{CredentialType: organizationCredentialType, RotationPeriod: time.Hour, RotationWindow: time.Hour},
+			expected: "requires rotation_schedule",
+		},
+		"invalid schedule": {
+			role:     terraformRoleEntry{CredentialType: organizationCredentialType, RotationSchedule: "not a schedule"},
+			expected: "invalid rotation_schedule",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.role.validateRotation()
+			if tc.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestRoleNextRotation(t *testing.T) {
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	role := terraformRoleEntry{RotationPeriod: time.Hour}
+	next, err := role.nextRotationAfter(last)
+	require.NoError(t, err)
+	require.Equal(t, last.Add(time.Hour), next)
+
+	role = terraformRoleEntry{RotationSchedule: "0 2 * * *"}
+	next, err = role.nextRotationAfter(last)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 1, 2, 2, 0, 0, 0, time.UTC), next)
+
+	role = terraformRoleEntry{}
+	next, err = role.nextRotationAfter(last)
+	require.NoError(t, err)
+	require.True(t, next.IsZero())
+}
+
+func TestRotateDueRolesMissedWindow(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	scheduled := time.Date(2026, 1, 2, 2, 0, 0, 0, time.UTC)
+	err := setRole(ctx, s, roleName, &terraformRoleEntry{
+		Name:             roleName,
+		Organization:     "acme",
+		CredentialType:   organizationCredentialType,
+		RotationSchedule: "0 2 * * *",
+		RotationWindow:   time.Hour,
+		NextRotation:     scheduled,
+	})
+	require.NoError(t, err)
+
+	// past the window, so the rotation is skipped without calling the API
+	now := scheduled.Add(2 * time.Hour)
+	require.NoError(t, b.rotateDueRoles(ctx, s, now))
+
+	roleEntry, err := b.getRole(ctx, s, roleName)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 1, 3, 2, 0, 0, 0, time.UTC), roleEntry.NextRotation)
+	require.True(t, roleEntry.LastRotated.IsZero())
+}
+
+func TestRotationDisabledClearsNextRotation(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	err := setRole(ctx, s, roleName, &terraformRoleEntry{
+		Name:           roleName,
+		UserID:         "user-123",
+		CredentialType: userCredentialType,
+		NextRotation:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"rotation_period": 0,
+		"skip_validation": true,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	roleEntry, err := b.getRole(ctx, s, roleName)
+	require.NoError(t, err)
+	require.True(t, roleEntry.NextRotation.IsZero())
+}
+
+func TestRotationsOfRoleAreSerialized(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var inFlight, maxInFlight, created int
+	currentTokenID := ""
+
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+		},
+		"POST /api/v2/organizations/acme/authentication-token": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+
+			// leave room for the other rotation to start
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			created++
+			currentTokenID = fmt.Sprintf("at-%d", created)
+			tokenID := currentTokenID
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"data":{"id":%q,"type":"authentication-tokens","attributes":{"token":"token-%s"}}}`, tokenID, tokenID)
+		},
+	})
+
+	err := setRole(ctx, s, roleName, &terraformRoleEntry{
+		Name:           roleName,
+		Organization:   "acme",
+		CredentialType: organizationCredentialType,
+		RotationPeriod: time.Hour,
+		NextRotation:   time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- b.rotateDueRoles(ctx, s, time.Now())
+	}()
+	go func() {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-role/" + roleName,
+			Storage:   s,
+		})
+		errs <- err
+	}()
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, maxInFlight)
+
+	// the role holds the token that was created last
+	roleEntry, err := b.getRole(ctx, s, roleName)
+	require.NoError(t, err)
+	require.Equal(t, currentTokenID, roleEntry.TokenID)
+}
@@ -0,0 +1,56 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// getSalt returns the salt of the mount used to hash tokens, creating it on
+// first use.
+func (b *tfBackend) getSalt(ctx context.Context, s logical.Storage) (*salt.Salt, error) {
+	b.saltLock.RLock()
+	if b.salt != nil {
+		defer b.saltLock.RUnlock()
+		return b.salt, nil
+	}
+	b.saltLock.RUnlock()
+
+	b.saltLock.Lock()
+	defer b.saltLock.Unlock()
+
+	if b.salt != nil {
+		return b.salt, nil
+	}
+
+	tokenSalt, err := salt.NewSalt(ctx, s, &salt.Config{
+		HashFunc: salt.SHA256Hash,
+		Location: salt.DefaultLocation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.salt = tokenSalt
+	return tokenSalt, nil
+}
+
+func (b *tfBackend) resetSalt() {
+	b.saltLock.Lock()
+	defer b.saltLock.Unlock()
+	b.salt = nil
+}
+
+// hashToken returns the salted HMAC of a token, used to find what the mount
+// knows about a token without storing the token itself.
+func (b *tfBackend) hashToken(ctx context.Context, s logical.Storage, token string) (string, error) {
+	tokenSalt, err := b.getSalt(ctx, s)
+	if err != nil {
+		return "", err
+	}
+	return tokenSalt.GetHMAC(token), nil
+}
@@ -0,0 +1,169 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const storageVersionPath = "storage-version"
+
+// storageVersion records the last storage migration applied to the mount.
+type storageVersion struct {
+	Version int `json:"version"`
+}
+
+// storageMigration upgrades the role and config entries of a mount to
+// version. Migrations must be safe to run more than once, since a failure
+// part way through leaves the version unchanged and the migration is run again
+// on the next initialization.
+type storageMigration struct {
+	version     int
+	description string
+	migrate     func(ctx context.Context, b *tfBackend, s logical.Storage) error
+}
+
+var storageMigrations = []storageMigration{
+	{
+		version:     1,
+		description: "fill in credential_type and name of roles and defaults of config",
+		migrate:     migrateStorageV1,
+	},
+}
+
+// currentStorageVersion is the version of the latest storage migration.
+func currentStorageVersion() int {
+	return storageMigrations[len(storageMigrations)-1].version
+}
+
+func (b *tfBackend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	// migrations write to storage, which only the active node of a primary
+	// cluster or a local mount may do
+	if !b.WriteSafeReplicationState() {
+		return nil
+	}
+
+	return b.migrateStorage(ctx, req.Storage)
+}
+
+// migrateStorage applies the storage migrations the mount has not seen yet,
+// recording the version after each one.
+func (b *tfBackend) migrateStorage(ctx context.Context, s logical.Storage) error {
+	version, err := getStorageVersion(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range storageMigrations {
+		if migration.version <= version.Version {
+			continue
+		}
+
+		b.Logger().Info("migrating storage", "from_version", version.Version, "to_version", migration.version, "migration", migration.description)
+		if err := migration.migrate(ctx, b, s); err != nil {
+			return fmt.Errorf("error migrating storage to version %d: %w", migration.version, err)
+		}
+
+		version.Version = migration.version
+		if err := putStorageVersion(ctx, s, version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getStorageVersion(ctx context.Context, s logical.Storage) (*storageVersion, error) {
+	entry, err := s.Get(ctx, storageVersionPath)
+	if err != nil {
+		return nil, err
+	}
+
+	version := new(storageVersion)
+	if entry == nil {
+		return version, nil
+	}
+
+	if err := entry.DecodeJSON(version); err != nil {
+		return nil, fmt.Errorf("error reading storage version: %w", err)
+	}
+	return version, nil
+}
+
+func putStorageVersion(ctx context.Context, s logical.Storage, version *storageVersion) error {
+	entry, err := logical.StorageEntryJSON(storageVersionPath, version)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+// migrateStorageV1 fills in the credential_type of roles written before it
+// existed, the name of roles written without one, and the address and
+// base_path defaults of the config.
+func migrateStorageV1(ctx context.Context, b *tfBackend, s logical.Storage) error {
+	names, err := s.List(ctx, "role/")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		roleEntry, err := b.getRole(ctx, s, name)
+		if err != nil {
+			return err
+		}
+
+		if roleEntry == nil {
+			continue
+		}
+
+		changed := false
+		if roleEntry.Name == "" {
+			roleEntry.Name = name
+			changed = true
+		}
+
+		if roleEntry.CredentialType == "" {
+			roleEntry.CredentialType = roleEntry.inferCredentialType()
+			b.Logger().Info("inferred credential type of role", "role", name, "credential_type", roleEntry.CredentialType)
+			changed = true
+		}
+
+		if !changed {
+			continue
+		}
+
+		if err := setRole(ctx, s, name, roleEntry); err != nil {
+			return err
+		}
+	}
+
+	config, err := getConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if config == nil || (config.Address != "" && config.BasePath != "") {
+		return nil
+	}
+
+	if config.Address == "" {
+		config.Address = defaultAddress
+	}
+	if config.BasePath == "" {
+		config.BasePath = defaultBasePath
+	}
+	b.Logger().Info("filled in config defaults", "address", config.Address, "base_path", config.BasePath)
+
+	entry, err := logical.StorageEntryJSON(configStoragePath, config)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
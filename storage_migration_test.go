@@ -0,0 +1,76 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageMigration(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	// roles as written before credential_type existed
+	for name, roleEntry := range map[string]*terraformRoleEntry{
+		"org":    {Name: "org", Organization: "acme", Token: "org-token"},
+		"legacy": {Name: "legacy", Organization: "acme", TeamID: "team-123"},
+		"user":   {UserID: "user-123"},
+		"team":   {Name: "team", TeamID: "team-123", CredentialType: teamCredentialType},
+	} {
+		require.NoError(t, setRole(ctx, s, name, roleEntry))
+	}
+
+	entry, err := logical.StorageEntryJSON(configStoragePath, &tfConfig{Token: "config-token"})
+	require.NoError(t, err)
+	require.NoError(t, s.Put(ctx, entry))
+
+	require.NoError(t, b.Initialize(ctx, &logical.InitializationRequest{Storage: s}))
+
+	expected := map[string]string{
+		"org":    organizationCredentialType,
+		"legacy": teamLegacyCredentialType,
+		"user":   userCredentialType,
+		"team":   teamCredentialType,
+	}
+	for name, credentialType := range expected {
+		roleEntry, err := b.getRole(ctx, s, name)
+		require.NoError(t, err)
+		require.Equal(t, name, roleEntry.Name)
+		require.Equal(t, credentialType, roleEntry.CredentialType, name)
+	}
+
+	config, err := getConfig(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, "config-token", config.Token)
+	require.Equal(t, defaultAddress, config.Address)
+	require.Equal(t, defaultBasePath, config.BasePath)
+
+	version, err := getStorageVersion(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, currentStorageVersion(), version.Version)
+
+	t.Run("idempotent", func(t *testing.T) {
+		// a role written with an empty credential_type after the migration
+		// is left alone, since the mount is already at the current version
+		require.NoError(t, setRole(ctx, s, "late", &terraformRoleEntry{Name: "late", UserID: "user-456"}))
+
+		require.NoError(t, b.Initialize(ctx, &logical.InitializationRequest{Storage: s}))
+
+		roleEntry, err := b.getRole(ctx, s, "late")
+		require.NoError(t, err)
+		require.Empty(t, roleEntry.CredentialType)
+
+		// running a migration again does not change migrated roles
+		require.NoError(t, migrateStorageV1(ctx, b, s))
+		for name, credentialType := range expected {
+			roleEntry, err := b.getRole(ctx, s, name)
+			require.NoError(t, err)
+			require.Equal(t, credentialType, roleEntry.CredentialType, name)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// teamTokenExpiryMaxTTL gives team tokens an upstream expiry of the max_ttl
+	// of the role or the system, fixed when the token is created.
+	teamTokenExpiryMaxTTL = "max_ttl"
+	// teamTokenExpiryLease gives team tokens an upstream expiry just beyond
+	// the lease TTL. Renewals replace the token when the lease would outlive it.
+	teamTokenExpiryLease = "lease"
+
+	defaultTeamTokenExpiryBuffer = 5 * time.Minute
+)
+
+func teamTokenExpiry_Values() []string {
+	return []string{
+		teamTokenExpiryMaxTTL,
+		teamTokenExpiryLease,
+	}
+}
+
+// leaseBoundExpiry reports whether the upstream expiry of the team tokens of
+// the role follows their lease.
+func (r *terraformRoleEntry) leaseBoundExpiry() bool {
+	return r.CredentialType == teamCredentialType && r.TeamTokenExpiry == teamTokenExpiryLease
+}
+
+// teamTokenExpiryBufferOrDefault returns how far beyond the lease TTL the
+// upstream expiry of a lease-bound team token is set.
+func (r *terraformRoleEntry) teamTokenExpiryBufferOrDefault() time.Duration {
+	if r.TeamTokenExpiryBuffer > 0 {
+		return r.TeamTokenExpiryBuffer
+	}
+	return defaultTeamTokenExpiryBuffer
+}
+
+// validateTeamTokenExpiry checks the team token expiry settings of a role.
+func (r *terraformRoleEntry) validateTeamTokenExpiry() error {
+	if r.TeamTokenExpiry != "" && !strutil.StrListContains(teamTokenExpiry_Values(), r.TeamTokenExpiry) {
+		return fmt.Errorf("unrecognized team_token_expiry: %s", r.TeamTokenExpiry)
+	}
+
+	if r.TeamTokenExpiryBuffer < 0 {
+		return errors.New("team_token_expiry_buffer cannot be negative")
+	}
+
+	if r.TeamTokenExpiry == teamTokenExpiryLease && r.CredentialType != teamCredentialType {
+		return errors.New("team_token_expiry = lease is only supported with credential_type = team")
+	}
+
+	return nil
+}
+
+// leaseTokenExpiry returns how long from now a lease-bound team token should
+// stay valid for a lease of ttl, without outliving the max TTL of a lease
+// issued at issueTime.
+func (r *terraformRoleEntry) leaseTokenExpiry(ttl time.Duration, maxTTL time.Duration, issueTime time.Time) time.Duration {
+	buffer := r.teamTokenExpiryBufferOrDefault()
+	expiry := ttl + buffer
+
+	if maxTTL > 0 && !issueTime.IsZero() {
+		if untilMax := time.Until(issueTime.Add(maxTTL)) + buffer; untilMax < expiry {
+			expiry = untilMax
+		}
+	}
+
+	return expiry
+}
+
+// reissueLeaseToken replaces the team token of a lease with one that expires
+// just beyond the renewed lease. The previous token is left to expire on its
+// own, so clients that renew without reading the new token keep working for
+// the remainder of its buffer. The lease's internal data is updated to the
+// new token and keeps the previous ones, expiring at previousExpiredAt, which
+// are revoked with the lease.
+func (b *tfBackend) reissueLeaseToken(ctx context.Context, req *logical.Request, roleEntry *terraformRoleEntry, ttl time.Duration, maxTTL time.Duration, previousExpiredAt time.Time) (*terraformToken, error) {
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	description, err := tokenDescription(req, roleEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := tokenOptions{
+		Description: description,
+		MaxTTL:      roleEntry.leaseTokenExpiry(ttl, maxTTL, req.Secret.IssueTime),
+	}
+
+	token, err := createTeamTokenWithOptions(ctx, client, *roleEntry, opts, b.System().MaxLeaseTTL())
+	if err != nil {
+		return nil, fmt.Errorf("error replacing team token: %w", err)
+	}
+
+	previousTokenID, _ := req.Secret.InternalData["token_id"].(string)
+	if previousTokenID != "" {
+		req.Secret.InternalData[replacedTokensKey] = addReplacedToken(req.Secret, previousTokenID, previousExpiredAt)
+	}
+
+	req.Secret.InternalData["token_id"] = token.ID
+	if token.ExpiredAt.IsZero() {
+		delete(req.Secret.InternalData, "expired_at")
+	} else {
+		req.Secret.InternalData["expired_at"] = token.ExpiredAt.Format(time.RFC3339)
+	}
+
+	now := time.Now()
+	if err := b.recordIssuedToken(ctx, req.Storage, &issuedToken{
+		TokenID:        token.ID,
+		Role:           roleEntry.Name,
+		CredentialType: roleEntry.CredentialType,
+		LeaseID:        req.Secret.LeaseID,
+		EntityID:       req.EntityID,
+		RequestID:      req.ID,
+		IssuedAt:       now,
+		ExpiredAt:      token.ExpiredAt,
+		LeaseExpiresAt: b.leaseExpiresAt(req.Secret.IssueTime, maxTTL),
+	}, token.Token); err != nil {
+		b.Logger().Warn("unable to record issued token", "role", roleEntry.Name, "token_id", token.ID, "error", err)
+	}
+	if err := updateIssuedToken(ctx, req.Storage, previousTokenID, func(record *issuedToken) {
+		record.ReplacedBy = token.ID
+	}); err != nil {
+		b.Logger().Warn("unable to record replaced token", "role", roleEntry.Name, "token_id", previousTokenID, "error", err)
+	}
+
+	b.sendEvent(ctx, eventTypeCredsIssue, append(tokenEventMetadata(roleEntry.Name, roleEntry.CredentialType, token.ID, token.ExpiredAt),
+		"replaced_token_id", previousTokenID)...)
+
+	return token, nil
+}
+
+// replacedTokensKey holds the tokens of a lease that were replaced on renewal
+// but have not expired yet, mapped to their expiry in RFC 3339 format.
+const replacedTokensKey = "replaced_tokens"
+
+// addReplacedToken returns the replaced tokens of the secret with tokenID
+// added and the tokens that have since expired removed.
+func addReplacedToken(secret *logical.Secret, tokenID string, expiredAt time.Time) map[string]interface{} {
+	tokens := make(map[string]interface{})
+	for id, expiry := range replacedTokens(secret) {
+		if expiry.IsZero() || time.Now().Before(expiry) {
+			tokens[id] = expiry.Format(time.RFC3339)
+		}
+	}
+
+	tokens[tokenID] = ""
+	if !expiredAt.IsZero() {
+		tokens[tokenID] = expiredAt.Format(time.RFC3339)
+	}
+
+	return tokens
+}
+
+// replacedTokens returns the replaced tokens of the secret and their expiry,
+// which is zero when unknown.
+func replacedTokens(secret *logical.Secret) map[string]time.Time {
+	raw, _ := secret.InternalData[replacedTokensKey].(map[string]interface{})
+
+	tokens := make(map[string]time.Time, len(raw))
+	for id, expiryRaw := range raw {
+		expiry, _ := time.Parse(time.RFC3339, fmt.Sprint(expiryRaw))
+		tokens[id] = expiry
+	}
+
+	return tokens
+}
+
+// revokeReplacedTokens revokes the replaced tokens of a lease that have not
+// expired yet. They expire shortly anyway, so failures are only logged.
+func (b *tfBackend) revokeReplacedTokens(ctx context.Context, c *client, s logical.Storage, secret *logical.Secret) {
+	for tokenID, expiredAt := range replacedTokens(secret) {
+		if !expiredAt.IsZero() && !time.Now().Before(expiredAt) {
+			continue
+		}
+
+		if err := c.TeamTokens.DeleteByID(ctx, tokenID); err != nil && !errors.Is(err, tfe.ErrResourceNotFound) {
+			b.Logger().Warn("unable to revoke replaced team token", append([]interface{}{"replaced_token_id", tokenID, "error", err}, logFields(ctx)...)...)
+			continue
+		}
+
+		if err := updateIssuedToken(ctx, s, tokenID, func(record *issuedToken) {
+			if record.RevokedAt.IsZero() {
+				record.RevokedAt = time.Now()
+			}
+		}); err != nil {
+			b.Logger().Warn("unable to record token revocation", append([]interface{}{"replaced_token_id", tokenID, "error", err}, logFields(ctx)...)...)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamTokenExpiryValidation(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		data     map[string]interface{}
+		expected string
+	}{
+		"unknown mode": {
+			data:     map[string]interface{}{"team_id": "team-123", "credential_type": "team", "team_token_expiry": "forever"},
+			expected: "unrecognized team_token_expiry",
+		},
+		"user role": {
+			data:     map[string]interface{}{"user_id": "user-123", "team_token_expiry": "lease"},
+			expected: "only supported with credential_type = team",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.data["skip_validation"] = true
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "role/expiry",
+				Storage:   s,
+				Data:      tc.data,
+			})
+			require.NoError(t, err)
+			require.True(t, resp.IsError())
+			require.Contains(t, resp.Error().Error(), tc.expected)
+		})
+	}
+
+	t.Run("read", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/expiry",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"team_id":           "team-123",
+				"credential_type":   "team",
+				"team_token_expiry": "lease",
+				"skip_validation":   true,
+			},
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "role/expiry",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "lease", resp.Data["team_token_expiry"])
+		require.Equal(t, defaultTeamTokenExpiryBuffer.Seconds(), resp.Data["team_token_expiry_buffer"])
+	})
+}
+
+func TestLeaseTokenExpiry(t *testing.T) {
+	roleEntry := &terraformRoleEntry{
+		CredentialType:        teamCredentialType,
+		TeamTokenExpiry:       teamTokenExpiryLease,
+		TeamTokenExpiryBuffer: time.Minute,
+	}
+
+	require.Equal(t, time.Hour+time.Minute, roleEntry.leaseTokenExpiry(time.Hour, 0, time.Time{}))
+	require.Equal(t, time.Hour+time.Minute, roleEntry.leaseTokenExpiry(time.Hour, 8*time.Hour, time.Now()))
+
+	// the token does not outlive the max TTL of the lease
+	expiry := roleEntry.leaseTokenExpiry(time.Hour, 8*time.Hour, time.Now().Add(-7*time.Hour-30*time.Minute))
+	require.LessOrEqual(t, expiry, 31*time.Minute)
+	require.Greater(t, expiry, 30*time.Minute)
+}
+
+func TestRenewReissuesLeaseBoundTeamToken(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var deleted []string
+	soon := time.Now().Add(10 * time.Minute).UTC().Truncate(time.Second)
+	later := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	deleteToken := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		deleted = append(deleted, path.Base(r.URL.Path))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"POST /api/v2/teams/team-123/authentication-tokens": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"data":{"id":"at-new","type":"authentication-tokens","attributes":{"token":"new-token","expired-at":%q}}}`, time.Now().Add(time.Hour+5*time.Minute).UTC().Format(time.RFC3339))
+		},
+		"DELETE /api/v2/authentication-tokens/at-soon": deleteToken,
+		"DELETE /api/v2/authentication-tokens/at-new":  deleteToken,
+		"/api/v2/authentication-tokens/at-soon": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"data":{"id":"at-soon","type":"authentication-tokens","attributes":{"expired-at":%q}}}`, soon.Format(time.RFC3339))
+		},
+		"/api/v2/authentication-tokens/at-later": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"data":{"id":"at-later","type":"authentication-tokens","attributes":{"expired-at":%q}}}`, later.Format(time.RFC3339))
+		},
+	})
+
+	err := setRole(ctx, s, roleName, &terraformRoleEntry{
+		Name:            roleName,
+		TeamID:          "team-123",
+		CredentialType:  teamCredentialType,
+		TeamTokenExpiry: teamTokenExpiryLease,
+		TTL:             time.Hour,
+		MaxTTL:          8 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	renew := func(tokenID string) (*logical.Response, error) {
+		secret := &logical.Secret{InternalData: map[string]interface{}{
+			"role":            roleName,
+			"token_id":        tokenID,
+			"credential_type": teamCredentialType,
+		}}
+		secret.IssueTime = time.Now().Add(-time.Minute)
+		return b.terraformTokenRenew(ctx, &logical.Request{
+			Storage: s,
+			Secret:  secret,
+		}, nil)
+	}
+
+	t.Run("token outlives the lease", func(t *testing.T) {
+		resp, err := renew("at-later")
+		require.NoError(t, err)
+		require.Nil(t, resp.Data)
+		require.Equal(t, "at-later", resp.Secret.InternalData["token_id"])
+		require.Equal(t, time.Hour, resp.Secret.TTL)
+		require.Equal(t, 8*time.Hour, resp.Secret.MaxTTL)
+	})
+
+	t.Run("token expires before the lease", func(t *testing.T) {
+		resp, err := renew("at-soon")
+		require.NoError(t, err)
+		require.Equal(t, "new-token", resp.Data["token"])
+		require.Equal(t, "at-new", resp.Data["token_id"])
+		require.NotNil(t, resp.Data["expired_at"])
+		require.Equal(t, "at-new", resp.Secret.InternalData["token_id"])
+		require.Equal(t, time.Hour, resp.Secret.TTL)
+		require.Equal(t, 8*time.Hour, resp.Secret.MaxTTL)
+
+		// clients still holding the previous token keep it until it expires
+		mu.Lock()
+		require.Empty(t, deleted)
+		mu.Unlock()
+		require.Equal(t, map[string]interface{}{"at-soon": soon.Format(time.RFC3339)}, resp.Secret.InternalData[replacedTokensKey])
+
+		// and it is revoked along with the lease
+		_, err = b.terraformTokenRevoke(ctx, &logical.Request{
+			Storage: s,
+			Secret:  resp.Secret,
+		}, nil)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, []string{"at-new", "at-soon"}, deleted)
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-tfe"
+)
+
+// resolveTeamID looks up the ID of the team with the given name in the
+// organization. Team names are unique within an organization. A missing team
+// is reported as tfe.ErrResourceNotFound.
+func resolveTeamID(ctx context.Context, c *client, organization string, teamName string) (string, error) {
+	teams, err := c.Teams.List(ctx, organization, &tfe.TeamListOptions{
+		Names: []string{teamName},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error looking up team %q in organization %q: %w", teamName, organization, err)
+	}
+
+	for _, team := range teams.Items {
+		if team != nil && team.Name == teamName {
+			return team.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("team %q not found in organization %q: %w", teamName, organization, tfe.ErrResourceNotFound)
+}
+
+// resolveUserID looks up the ID of a member of the organization by email
+// address or username. Exactly one of email or username must be set.
+func resolveUserID(ctx context.Context, c *client, organization string, email string, username string) (string, error) {
+	opts := &tfe.OrganizationMembershipListOptions{
+		ListOptions: tfe.ListOptions{PageSize: 100},
+		Include:     []tfe.OrgMembershipIncludeOpt{tfe.OrgMembershipUser},
+	}
+
+	lookup := email
+	if email != "" {
+		opts.Emails = []string{email}
+	} else {
+		lookup = username
+		opts.Query = username
+	}
+
+	// a username query also matches partial names and emails, so the match
+	// may be on any page
+	for {
+		memberships, err := c.OrganizationMemberships.List(ctx, organization, opts)
+		if err != nil {
+			return "", fmt.Errorf("error looking up user %q in organization %q: %w", lookup, organization, err)
+		}
+
+		for _, membership := range memberships.Items {
+			if membership == nil || membership.User == nil {
+				continue
+			}
+
+			if email != "" && strings.EqualFold(membership.Email, email) {
+				return membership.User.ID, nil
+			}
+
+			if username != "" && membership.User.Username == username {
+				return membership.User.ID, nil
+			}
+		}
+
+		if memberships.Pagination == nil || memberships.Pagination.NextPage == 0 {
+			break
+		}
+		opts.PageNumber = memberships.Pagination.NextPage
+	}
+
+	return "", fmt.Errorf("user %q not found in organization %q", lookup, organization)
+}
@@ -0,0 +1,214 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	syncTargetWorkspace   = "workspace"
+	syncTargetVariableSet = "variable_set"
+
+	syncStatusSynced = "synced"
+	syncStatusFailed = "failed"
+)
+
+func syncVariableCategory_Values() []string {
+	return []string{
+		string(tfe.CategoryTerraform),
+		string(tfe.CategoryEnv),
+	}
+}
+
+// roleSyncStatus records the outcome of the last attempt to write the token
+// of a role to one of its sync targets.
+type roleSyncStatus struct {
+	Target      string    `json:"target"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSynced  time.Time `json:"last_synced,omitempty"`
+}
+
+func (s roleSyncStatus) toResponseData() map[string]interface{} {
+	data := map[string]interface{}{
+		"target":       s.Target,
+		"type":         s.Type,
+		"status":       s.Status,
+		"last_attempt": s.LastAttempt,
+	}
+	if s.Error != "" {
+		data["error"] = s.Error
+	}
+	if !s.LastSynced.IsZero() {
+		data["last_synced"] = s.LastSynced
+	}
+	return data
+}
+
+// hasSyncTargets reports whether the role pushes its token to workspaces or
+// variable sets.
+func (r *terraformRoleEntry) hasSyncTargets() bool {
+	return len(r.SyncWorkspaceIDs) > 0 || len(r.SyncVariableSetIDs) > 0
+}
+
+// syncRoleToken writes the token of the role as a sensitive variable to each
+// of its sync targets and stores the per-target outcome on the role. A failing
+// target does not stop the others.
+func (b *tfBackend) syncRoleToken(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry) error {
+	if !roleEntry.hasSyncTargets() {
+		return nil
+	}
+
+	client, err := b.getClient(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	previous := make(map[string]roleSyncStatus, len(roleEntry.SyncStatus))
+	for _, status := range roleEntry.SyncStatus {
+		previous[status.Type+"/"+status.Target] = status
+	}
+
+	category := tfe.CategoryType(roleEntry.SyncVariableCategory)
+	if category == "" {
+		category = tfe.CategoryTerraform
+	}
+
+	var statuses []roleSyncStatus
+	record := func(targetType string, target string, err error) {
+		status := roleSyncStatus{
+			Target:      target,
+			Type:        targetType,
+			Status:      syncStatusSynced,
+			LastAttempt: time.Now(),
+			LastSynced:  previous[targetType+"/"+target].LastSynced,
+		}
+		if err != nil {
+			b.Logger().Error("unable to sync role token", "role", roleEntry.Name, "target", target, "error", err)
+			b.recordError(statusOperationSync, fmt.Errorf("role %q, target %q: %w", roleEntry.Name, target, err))
+			status.Status = syncStatusFailed
+			status.Error = err.Error()
+		} else {
+			status.LastSynced = status.LastAttempt
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, workspaceID := range roleEntry.SyncWorkspaceIDs {
+		err := syncWorkspaceVariable(ctx, client, workspaceID, roleEntry.SyncVariableKey, category, roleEntry.Token)
+		record(syncTargetWorkspace, workspaceID, err)
+	}
+
+	for _, variableSetID := range roleEntry.SyncVariableSetIDs {
+		err := syncVariableSetVariable(ctx, client, variableSetID, roleEntry.SyncVariableKey, category, roleEntry.Token)
+		record(syncTargetVariableSet, variableSetID, err)
+	}
+
+	roleEntry.SyncStatus = statuses
+
+	return setRole(ctx, s, roleEntry.Name, roleEntry)
+}
+
+// syncWorkspaceVariable creates or updates a sensitive workspace variable.
+func syncWorkspaceVariable(ctx context.Context, c *client, workspaceID string, key string, category tfe.CategoryType, value string) error {
+	sensitive := true
+	opts := &tfe.VariableListOptions{
+		ListOptions: tfe.ListOptions{PageSize: 100},
+	}
+
+	for {
+		variables, err := c.Variables.List(ctx, workspaceID, opts)
+		if err != nil {
+			return fmt.Errorf("error listing variables of workspace %q: %w", workspaceID, err)
+		}
+
+		for _, variable := range variables.Items {
+			if variable.Key != key || variable.Category != category {
+				continue
+			}
+
+			_, err := c.Variables.Update(ctx, workspaceID, variable.ID, tfe.VariableUpdateOptions{
+				Value:     &value,
+				Sensitive: &sensitive,
+			})
+			if err != nil {
+				return fmt.Errorf("error updating variable %q of workspace %q: %w", key, workspaceID, err)
+			}
+			return nil
+		}
+
+		if variables.Pagination == nil || variables.Pagination.NextPage == 0 {
+			break
+		}
+		opts.PageNumber = variables.Pagination.NextPage
+	}
+
+	_, err := c.Variables.Create(ctx, workspaceID, tfe.VariableCreateOptions{
+		Key:       &key,
+		Value:     &value,
+		Category:  &category,
+		Sensitive: &sensitive,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating variable %q in workspace %q: %w", key, workspaceID, err)
+	}
+
+	return nil
+}
+
+// syncVariableSetVariable creates or updates a sensitive variable in a
+// variable set.
+func syncVariableSetVariable(ctx context.Context, c *client, variableSetID string, key string, category tfe.CategoryType, value string) error {
+	sensitive := true
+	opts := &tfe.VariableSetVariableListOptions{
+		ListOptions: tfe.ListOptions{PageSize: 100},
+	}
+
+	for {
+		variables, err := c.VariableSetVariables.List(ctx, variableSetID, opts)
+		if err != nil {
+			return fmt.Errorf("error listing variables of variable set %q: %w", variableSetID, err)
+		}
+
+		for _, variable := range variables.Items {
+			if variable.Key != key || variable.Category != category {
+				continue
+			}
+
+			_, err := c.VariableSetVariables.Update(ctx, variableSetID, variable.ID, &tfe.VariableSetVariableUpdateOptions{
+				Value:     &value,
+				Sensitive: &sensitive,
+			})
+			if err != nil {
+				return fmt.Errorf("error updating variable %q of variable set %q: %w", key, variableSetID, err)
+			}
+			return nil
+		}
+
+		if variables.Pagination == nil || variables.Pagination.NextPage == 0 {
+			break
+		}
+		opts.PageNumber = variables.Pagination.NextPage
+	}
+
+	_, err := c.VariableSetVariables.Create(ctx, variableSetID, &tfe.VariableSetVariableCreateOptions{
+		Key:       &key,
+		Value:     &value,
+		Category:  &category,
+		Sensitive: &sensitive,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating variable %q in variable set %q: %w", key, variableSetID, err)
+	}
+
+	return nil
+}
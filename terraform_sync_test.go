@@ -0,0 +1,108 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/stretchr/testify/require"
+)
+
+const envVarTerraformWorkspaceID = "TF_WORKSPACE_ID"
+
+func TestRoleSyncTargetValidation(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	cases := map[string]struct {
+		data     map[string]interface{}
+		expected string
+	}{
+		"user role": {
+			data: map[string]interface{}{
+				"user_id":            "user-123",
+				"sync_workspace_ids": "ws-123",
+				"sync_variable_key":  "tfe_token",
+			},
+			expected: "only supported with credential_type = organization or team_legacy",
+		},
+		"missing key": {
+			data: map[string]interface{}{
+				"organization":          "acme",
+				"sync_variable_set_ids": "varset-123",
+			},
+			expected: "must provide sync_variable_key",
+		},
+		"unknown category": {
+			data: map[string]interface{}{
+				"organization":           "acme",
+				"sync_workspace_ids":     "ws-123",
+				"sync_variable_key":      "tfe_token",
+				"sync_variable_category": "secret",
+			},
+			expected: "unrecognized sync_variable_category",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := testTokenRoleCreate(t, b, s, roleName, tc.data)
+			require.NoError(t, err)
+			require.True(t, resp.IsError())
+			require.Contains(t, resp.Error().Error(), tc.expected)
+		})
+	}
+}
+
+func TestAcceptanceRoleSyncWorkspace(t *testing.T) {
+	if !runAcceptanceTests {
+		t.SkipNow()
+	}
+
+	workspaceID, ok := os.LookupEnv(envVarTerraformWorkspaceID)
+	if !ok {
+		t.Skipf("%s is not set", envVarTerraformWorkspaceID)
+	}
+
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	organization := checkEnvVars(t, envVarTerraformOrganization)
+	token := checkEnvVars(t, envVarTerraformToken)
+
+	err := testConfigCreate(t, b, s, map[string]interface{}{
+		"token": token,
+	})
+	require.NoError(t, err)
+
+	resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"organization":       organization,
+		"sync_workspace_ids": workspaceID,
+		"sync_variable_key":  "vault_synced_token",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testTokenRoleRead(t, b, s)
+	require.NoError(t, err)
+	syncStatus := resp.Data["sync_status"].([]map[string]interface{})
+	require.Len(t, syncStatus, 1)
+	require.Equal(t, syncStatusSynced, syncStatus[0]["status"])
+
+	client, err := b.getClient(ctx, s)
+	require.NoError(t, err)
+	variables, err := client.Variables.List(ctx, workspaceID, nil)
+	require.NoError(t, err)
+
+	for _, variable := range variables.Items {
+		if variable.Key == "vault_synced_token" && variable.Category == tfe.CategoryTerraform {
+			require.True(t, variable.Sensitive)
+			require.NoError(t, client.Variables.Delete(ctx, workspaceID, variable.ID))
+			return
+		}
+	}
+	t.Fatal("synced variable not found in workspace")
+}
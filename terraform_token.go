@@ -7,7 +7,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/hashicorp/go-tfe"
@@ -70,15 +69,25 @@ func createTeamLegacyToken(ctx context.Context, c *client, teamID string) (*terr
 	}, nil
 }
 
-func createTeamTokenWithOptions(ctx context.Context, c *client, roleEntry terraformRoleEntry, systemMaxTTL time.Duration) (*terraformToken, error) {
+// tokenOptions holds the per-request settings for new user and team tokens.
+type tokenOptions struct {
+	Description string
+	// MaxTTL sets the upstream expiry of the token when non-zero
+	MaxTTL time.Duration
+}
+
+func createTeamTokenWithOptions(ctx context.Context, c *client, roleEntry terraformRoleEntry, opts tokenOptions, systemMaxTTL time.Duration) (*terraformToken, error) {
 	teamID := roleEntry.TeamID
+	description := opts.Description
 
-	uniqueDescription := fmt.Sprintf("%s(%d)", roleEntry.Description, rand.Intn(10000))
 	createOpts := tfe.TeamTokenCreateOptions{
-		Description: &uniqueDescription,
+		Description: &description,
 	}
 
-	maxTTL := max(roleEntry.MaxTTL, systemMaxTTL)
+	maxTTL := opts.MaxTTL
+	if maxTTL == 0 {
+		maxTTL = max(roleEntry.MaxTTL, systemMaxTTL)
+	}
 	if maxTTL > 0 {
 		expiredAt := time.Now().Add(maxTTL)
 		createOpts.ExpiredAt = &expiredAt
@@ -91,16 +100,23 @@ func createTeamTokenWithOptions(ctx context.Context, c *client, roleEntry terraf
 
 	return &terraformToken{
 		ID:          token.ID,
-		Description: uniqueDescription,
+		Description: description,
 		Token:       token.Token,
 		ExpiredAt:   token.ExpiredAt,
 	}, nil
 }
 
-func createUserToken(ctx context.Context, c *client, userID string, description string) (*terraformToken, error) {
-	token, err := c.UserTokens.Create(ctx, userID, tfe.UserTokenCreateOptions{
-		Description: description,
-	})
+func createUserToken(ctx context.Context, c *client, userID string, opts tokenOptions) (*terraformToken, error) {
+	createOpts := tfe.UserTokenCreateOptions{
+		Description: opts.Description,
+	}
+
+	if opts.MaxTTL > 0 {
+		expiredAt := time.Now().Add(opts.MaxTTL)
+		createOpts.ExpiredAt = &expiredAt
+	}
+
+	token, err := c.UserTokens.Create(ctx, userID, createOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -109,10 +125,40 @@ func createUserToken(ctx context.Context, c *client, userID string, description
 		ID:          token.ID,
 		Description: token.Description,
 		Token:       token.Token,
+		ExpiredAt:   token.ExpiredAt,
 	}, nil
 }
 
 func (b *tfBackend) terraformTokenRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	credentialType, _ := req.Secret.InternalData["credential_type"].(string)
+	role, _ := req.Secret.InternalData["role"].(string)
+	tokenID, _ := req.Secret.InternalData["token_id"].(string)
+	ctx = withLogFields(ctx, "request_id", req.ID, "role", role, "credential_type", credentialType, "token_id", tokenID)
+
+	resp, err := b.revokeLeaseToken(ctx, req)
+
+	emitRevocationMetric(credentialType, role, err)
+	b.recordError(statusOperationRevoke, err)
+
+	if err != nil {
+		b.Logger().Error("unable to revoke token", append([]interface{}{"error", err}, logFields(ctx)...)...)
+	} else {
+		if err := updateIssuedToken(ctx, req.Storage, tokenID, func(record *issuedToken) {
+			record.LeaseID = req.Secret.LeaseID
+			if record.RevokedAt.IsZero() {
+				record.RevokedAt = time.Now()
+			}
+		}); err != nil {
+			b.Logger().Warn("unable to record token revocation", append([]interface{}{"error", err}, logFields(ctx)...)...)
+		}
+		b.Logger().Debug("revoked token", logFields(ctx)...)
+		b.sendEvent(ctx, eventTypeCredsRevoke, tokenEventMetadata(role, credentialType, tokenID, time.Time{})...)
+	}
+
+	return resp, err
+}
+
+func (b *tfBackend) revokeLeaseToken(ctx context.Context, req *logical.Request) (*logical.Response, error) {
 	client, err := b.getClient(ctx, req.Storage)
 	if err != nil {
 		return nil, fmt.Errorf("error getting client: %w", err)
@@ -139,7 +185,7 @@ func (b *tfBackend) terraformTokenRevoke(ctx context.Context, req *logical.Reque
 
 	if isOrgToken(organization, teamID) {
 		// revoke org API token
-		if err := client.OrganizationTokens.Delete(ctx, organization); err != nil {
+		if err := client.OrganizationTokens.Delete(ctx, organization); err != nil && !errors.Is(err, tfe.ErrResourceNotFound) {
 			return nil, fmt.Errorf("error revoking organization token: %w", err)
 		}
 		return nil, nil
@@ -147,7 +193,7 @@ func (b *tfBackend) terraformTokenRevoke(ctx context.Context, req *logical.Reque
 
 	if isTeamToken(teamID) {
 		// revoke team API token
-		if err := client.TeamTokens.Delete(ctx, teamID); err != nil {
+		if err := client.TeamTokens.Delete(ctx, teamID); err != nil && !errors.Is(err, tfe.ErrResourceNotFound) {
 			return nil, fmt.Errorf("error revoking team token: %w", err)
 		}
 		return nil, nil
@@ -167,9 +213,30 @@ func (b *tfBackend) terraformTokenRevoke(ctx context.Context, req *logical.Reque
 		return nil, fmt.Errorf("secret is missing tokenID internal data")
 	}
 
-	if err := client.UserTokens.Delete(ctx, tokenID); err != nil {
-		return nil, fmt.Errorf("error revoking user token: %w", err)
+	record, err := getIssuedToken(ctx, req.Storage, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	// a token revoked through revoke-token is already gone upstream, and one
+	// that expired upstream before its lease is gone as well
+	if record == nil || !record.BreakGlass {
+		if err := client.UserTokens.Delete(ctx, tokenID); err != nil && !errors.Is(err, tfe.ErrResourceNotFound) {
+			return nil, fmt.Errorf("error revoking user token: %w", err)
+		}
 	}
+
+	// lease-bound team tokens replaced on renewal are revoked with the lease
+	b.revokeReplacedTokens(ctx, client, req.Storage, req.Secret)
+
+	if tracked, _ := req.Secret.InternalData["usage_tracked"].(bool); tracked {
+		if role, ok := req.Secret.InternalData["role"].(string); ok {
+			if err := b.releaseRoleToken(ctx, req.Storage, role, time.Time{}); err != nil {
+				b.Logger().Warn("unable to release role usage", "role", role, "error", err)
+			}
+		}
+	}
+
 	return nil, nil
 }
 
@@ -190,14 +257,181 @@ func (b *tfBackend) terraformTokenRenew(ctx context.Context, req *logical.Reques
 		return nil, errors.New("error retrieving role: role is nil")
 	}
 
+	if resp, err := b.checkLeaseAccess(req, roleEntry); resp != nil || err != nil {
+		return resp, err
+	}
+
+	tokenID, _ := req.Secret.InternalData["token_id"].(string)
+	ctx = withLogFields(withRoleLogFields(ctx, req, roleEntry), "token_id", tokenID)
+
+	expiredAt, err := b.verifyLeaseToken(ctx, req.Storage, req.Secret, roleEntry)
+	if err != nil {
+		b.Logger().Error("unable to renew lease", append([]interface{}{"error", err}, logFields(ctx)...)...)
+		b.recordError(statusOperationRenew, fmt.Errorf("role %q: %w", role, err))
+		return nil, err
+	}
+
+	if req.Secret.LeaseID != "" {
+		if err := updateIssuedToken(ctx, req.Storage, tokenID, func(record *issuedToken) {
+			record.LeaseID = req.Secret.LeaseID
+		}); err != nil {
+			b.Logger().Warn("unable to record token lease", append([]interface{}{"error", err}, logFields(ctx)...)...)
+		}
+	}
+
 	resp := &logical.Response{Secret: req.Secret}
 
-	if roleEntry.TTL > 0 {
-		resp.Secret.TTL = roleEntry.TTL
+	ttl := roleEntry.TTL
+	if requested, ok := secretDuration(req.Secret, "ttl"); ok {
+		ttl = requested
+	}
+
+	maxTTL := roleEntry.MaxTTL
+	if requested, ok := secretDuration(req.Secret, "max_ttl"); ok {
+		maxTTL = requested
 	}
-	if roleEntry.MaxTTL > 0 {
-		resp.Secret.MaxTTL = roleEntry.MaxTTL
+
+	// a lease-bound team token is replaced when the renewed lease would
+	// outlive it
+	if tokenID != "" && roleEntry.leaseBoundExpiry() {
+		leaseTTL := ttl
+		if leaseTTL == 0 {
+			leaseTTL = b.System().DefaultLeaseTTL()
+		}
+
+		if expiredAt.IsZero() || expiredAt.Before(time.Now().Add(leaseTTL)) {
+			token, err := b.reissueLeaseToken(ctx, req, roleEntry, leaseTTL, maxTTL, expiredAt)
+			if err != nil {
+				b.Logger().Error("unable to replace lease-bound team token", append([]interface{}{"error", err}, logFields(ctx)...)...)
+				b.recordError(statusOperationRenew, fmt.Errorf("role %q: %w", role, err))
+				return nil, err
+			}
+
+			resp.Data = map[string]interface{}{
+				"token":    token.Token,
+				"token_id": token.ID,
+			}
+			if token.Description != "" {
+				resp.Data["description"] = token.Description
+			}
+			if !token.ExpiredAt.IsZero() {
+				resp.Data["expired_at"] = token.ExpiredAt
+			}
+			expiredAt = token.ExpiredAt
+		}
+	}
+
+	// the lease cannot outlive the token
+	if !expiredAt.IsZero() {
+		remaining := time.Until(expiredAt).Truncate(time.Second)
+		if ttl == 0 || ttl > remaining {
+			ttl = remaining
+		}
+		// the token of a lease-bound role is replaced on renewal, so it does
+		// not limit how long the lease can be renewed for
+		if !req.Secret.IssueTime.IsZero() && !roleEntry.leaseBoundExpiry() {
+			if untilExpiry := expiredAt.Sub(req.Secret.IssueTime).Truncate(time.Second); maxTTL == 0 || maxTTL > untilExpiry {
+				maxTTL = untilExpiry
+			}
+		}
+	}
+
+	if ttl > 0 {
+		resp.Secret.TTL = ttl
+	}
+	if maxTTL > 0 {
+		resp.Secret.MaxTTL = maxTTL
 	}
 
 	return resp, nil
 }
+
+// verifyLeaseToken checks that the token of a lease still exists in Terraform
+// Cloud / Enterprise and has not expired, and returns its expiry, if any.
+// Leases issued without a token ID cannot be looked up and only have the
+// expiry recorded in their internal data checked.
+func (b *tfBackend) verifyLeaseToken(ctx context.Context, s logical.Storage, secret *logical.Secret, roleEntry *terraformRoleEntry) (time.Time, error) {
+	expiredAt, err := secretTime(secret, "expired_at")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tokenID, _ := secret.InternalData["token_id"].(string)
+	if tokenID != "" {
+		client, err := b.getClient(ctx, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error getting client: %w", err)
+		}
+
+		credentialType, _ := secret.InternalData["credential_type"].(string)
+		if credentialType == "" {
+			credentialType = roleEntry.CredentialType
+		}
+
+		var upstreamExpiredAt time.Time
+		if credentialType == teamCredentialType {
+			var token *tfe.TeamToken
+			token, err = client.TeamTokens.ReadByID(ctx, tokenID)
+			if token != nil {
+				upstreamExpiredAt = token.ExpiredAt
+			}
+		} else {
+			var token *tfe.UserToken
+			token, err = client.UserTokens.Read(ctx, tokenID)
+			if token != nil {
+				upstreamExpiredAt = token.ExpiredAt
+			}
+		}
+		if errors.Is(err, tfe.ErrResourceNotFound) {
+			return time.Time{}, fmt.Errorf("token %q no longer exists in Terraform Cloud / Enterprise, the lease cannot be renewed", tokenID)
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error verifying token %q: %w", tokenID, err)
+		}
+
+		if !upstreamExpiredAt.IsZero() {
+			expiredAt = upstreamExpiredAt
+		}
+	}
+
+	if !expiredAt.IsZero() && !time.Now().Before(expiredAt) {
+		return time.Time{}, fmt.Errorf("token %q expired at %s, the lease cannot be renewed", tokenID, expiredAt.Format(time.RFC3339))
+	}
+
+	return expiredAt, nil
+}
+
+// secretTime reads a time stored in RFC 3339 format in the internal data of
+// the secret.
+func secretTime(secret *logical.Secret, key string) (time.Time, error) {
+	raw, ok := secret.InternalData[key].(string)
+	if !ok || raw == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s in secret internal data: %w", key, err)
+	}
+	return t, nil
+}
+
+// secretDuration reads a duration stored in seconds in the internal data of
+// the secret.
+func secretDuration(secret *logical.Secret, key string) (time.Duration, bool) {
+	raw, ok := secret.InternalData[key]
+	if !ok {
+		return 0, false
+	}
+
+	switch v := raw.(type) {
+	case float64:
+		return time.Duration(v) * time.Second, true
+	case int64:
+		return time.Duration(v) * time.Second, true
+	case int:
+		return time.Duration(v) * time.Second, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-tfe"
+)
+
+// validateRoleTargets checks that the organization, team and user a role
+// refers to exist in Terraform Cloud / Enterprise, that the team belongs to
+// the role's organization, and that the configured token is allowed to manage
+// API tokens for them.
+func validateRoleTargets(ctx context.Context, c *client, roleEntry *terraformRoleEntry) error {
+	if roleEntry.Organization != "" {
+		org, err := c.Organizations.Read(ctx, roleEntry.Organization)
+		if err != nil {
+			if errors.Is(err, tfe.ErrResourceNotFound) {
+				return fmt.Errorf("organization %q not found or not visible to the configured token", roleEntry.Organization)
+			}
+			return fmt.Errorf("error reading organization %q: %w", roleEntry.Organization, err)
+		}
+
+		if roleEntry.CredentialType == organizationCredentialType && org.Permissions != nil && !org.Permissions.CanUpdateAPIToken {
+			return fmt.Errorf("configured token is not allowed to manage the API token of organization %q", roleEntry.Organization)
+		}
+	}
+
+	if roleEntry.TeamID != "" {
+		team, err := c.Teams.Read(ctx, roleEntry.TeamID)
+		if err != nil {
+			if errors.Is(err, tfe.ErrResourceNotFound) {
+				return fmt.Errorf("team %q not found or not visible to the configured token", roleEntry.TeamID)
+			}
+			return fmt.Errorf("error reading team %q: %w", roleEntry.TeamID, err)
+		}
+
+		if roleEntry.Organization != "" {
+			teamID, err := resolveTeamID(ctx, c, roleEntry.Organization, team.Name)
+			if err != nil && !errors.Is(err, tfe.ErrResourceNotFound) {
+				return fmt.Errorf("error checking that team %q belongs to organization %q: %w", roleEntry.TeamID, roleEntry.Organization, err)
+			}
+			if err != nil || teamID != team.ID {
+				return fmt.Errorf("team %q does not belong to organization %q", roleEntry.TeamID, roleEntry.Organization)
+			}
+		}
+
+		if team.Permissions != nil && !team.Permissions.CanUpdateMembership && !team.AllowMemberTokenManagement {
+			return fmt.Errorf("configured token is not allowed to manage API tokens of team %q", roleEntry.TeamID)
+		}
+	}
+
+	if roleEntry.UserID != "" {
+		current, err := c.Users.ReadCurrent(ctx)
+		if err != nil {
+			return fmt.Errorf("error reading the user of the configured token: %w", err)
+		}
+
+		isAdmin := current.IsAdmin != nil && *current.IsAdmin
+		if current.ID != roleEntry.UserID && !isAdmin {
+			return fmt.Errorf("configured token belongs to user %q and cannot manage API tokens of user %q", current.ID, roleEntry.UserID)
+		}
+	}
+
+	return nil
+}
+
+// validateAdoptedToken checks that an existing organization or team_legacy
+// token belongs to the organization or team of the role before the role takes
+// it over. The token ID must be given and must be the one token the
+// organization or team currently has, since any other API token of the
+// organization can read it too. The token must also be usable and able to
+// read the organization or team. It returns the ID of the token.
+func validateAdoptedToken(ctx context.Context, c *client, config *tfConfig, roleEntry *terraformRoleEntry, token string, tokenID string) (string, error) {
+	if tokenID == "" {
+		return "", errors.New("token_id is required to adopt a token")
+	}
+
+	currentTokenID, err := readCurrentTokenID(ctx, c, roleEntry.Organization, roleEntry.TeamID)
+	if err != nil {
+		return "", fmt.Errorf("error reading the current token: %w", err)
+	}
+
+	owner := fmt.Sprintf("organization %q", roleEntry.Organization)
+	if isTeamToken(roleEntry.TeamID) {
+		owner = fmt.Sprintf("team %q", roleEntry.TeamID)
+	}
+
+	if currentTokenID == "" {
+		return "", fmt.Errorf("%s has no API token to adopt", owner)
+	}
+
+	if tokenID != currentTokenID {
+		return "", fmt.Errorf("token %q is not the current API token of %s", tokenID, owner)
+	}
+
+	tokenClient, err := newClient(&tfConfig{
+		Address:  config.Address,
+		BasePath: config.BasePath,
+		Token:    token,
+	}, c.logger)
+	if err != nil {
+		return "", fmt.Errorf("error creating client for the adopted token: %w", err)
+	}
+
+	account, err := tokenClient.Users.ReadCurrent(ctx)
+	if err != nil {
+		return "", fmt.Errorf("adopted token is not valid: %w", err)
+	}
+
+	if !account.IsServiceAccount {
+		return "", fmt.Errorf("adopted token is a user token, not an API token of %s", owner)
+	}
+
+	if isTeamToken(roleEntry.TeamID) {
+		_, err = tokenClient.Teams.Read(ctx, roleEntry.TeamID)
+	} else {
+		_, err = tokenClient.Organizations.Read(ctx, roleEntry.Organization)
+	}
+	if err != nil {
+		return "", fmt.Errorf("adopted token cannot access %s: %w", owner, err)
+	}
+
+	return currentTokenID, nil
+}
@@ -0,0 +1,73 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/helper/template"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// descriptionTemplateData holds the fields available to a role's
+// description_template.
+type descriptionTemplateData struct {
+	RoleName      string
+	Description   string
+	MountPath     string
+	LeaseIDPrefix string
+	EntityID      string
+	DisplayName   string
+	RequestTime   string
+	UniqueID      string
+}
+
+func parseDescriptionTemplate(rawTemplate string) (template.StringTemplate, error) {
+	return template.NewTemplate(template.Template(rawTemplate))
+}
+
+// tokenDescription returns the description for a new user or team token of
+// the role. Without a description_template, team token descriptions keep the
+// role description followed by the ID of the Vault request in parentheses, or
+// a UUID for requests without one, so each token can be traced back to the
+// request that created it.
+func tokenDescription(req *logical.Request, roleEntry *terraformRoleEntry) (string, error) {
+	if roleEntry.DescriptionTemplate == "" {
+		if roleEntry.CredentialType != teamCredentialType {
+			return roleEntry.Description, nil
+		}
+
+		suffix := req.ID
+		if suffix == "" {
+			var err error
+			if suffix, err = uuid.GenerateUUID(); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%s(%s)", roleEntry.Description, suffix), nil
+	}
+
+	tmpl, err := parseDescriptionTemplate(roleEntry.DescriptionTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid description_template: %w", err)
+	}
+
+	uniqueID, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	return tmpl.Generate(descriptionTemplateData{
+		RoleName:      roleEntry.Name,
+		Description:   roleEntry.Description,
+		MountPath:     req.MountPoint,
+		LeaseIDPrefix: req.MountPoint + req.Path,
+		EntityID:      req.EntityID,
+		DisplayName:   req.DisplayName,
+		RequestTime:   time.Now().UTC().Format(time.RFC3339),
+		UniqueID:      uniqueID,
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenDescription(t *testing.T) {
+	req := &logical.Request{
+		MountPoint:  "terraform/",
+		Path:        "creds/ci",
+		EntityID:    "entity-1",
+		DisplayName: "token-ci",
+	}
+
+	t.Run("default team description", func(t *testing.T) {
+		role := &terraformRoleEntry{
+			Name:           "ci",
+			Description:    "ci",
+			CredentialType: teamCredentialType,
+		}
+
+		description, err := tokenDescription(&logical.Request{ID: "4c9e5a1f-0d2b-4e2a-9f1e-6a7b8c9d0e1f"}, role)
+		require.NoError(t, err)
+		require.Equal(t, "ci(4c9e5a1f-0d2b-4e2a-9f1e-6a7b8c9d0e1f)", description)
+
+		// requests without an ID get a UUID instead
+		first, err := tokenDescription(req, role)
+		require.NoError(t, err)
+		require.Regexp(t, regexp.MustCompile(`^ci\([0-9a-f-]{36}\)$`), first)
+
+		second, err := tokenDescription(req, role)
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+	})
+
+	t.Run("default user description", func(t *testing.T) {
+		description, err := tokenDescription(req, &terraformRoleEntry{
+			Name:           "ci",
+			Description:    "ci",
+			CredentialType: userCredentialType,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "ci", description)
+	})
+
+	t.Run("template", func(t *testing.T) {
+		role := &terraformRoleEntry{
+			Name:                "ci",
+			CredentialType:      teamCredentialType,
+			DescriptionTemplate: "{{.MountPath}}{{.RoleName}} {{.LeaseIDPrefix}} {{.EntityID}} {{.DisplayName}} {{.UniqueID}}",
+		}
+
+		first, err := tokenDescription(req, role)
+		require.NoError(t, err)
+		require.Regexp(t, regexp.MustCompile(`^terraform/ci terraform/creds/ci entity-1 token-ci [0-9a-f-]{36}$`), first)
+
+		second, err := tokenDescription(req, role)
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+	})
+}
+
+func TestRoleDescriptionTemplateValidation(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"user_id":              "user-123",
+		"description_template": "{{.RoleName",
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), "invalid description_template")
+
+	resp, err = testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"organization":         "acme",
+		"description_template": "{{.RoleName}}",
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), "only supported with credential_type = user or team")
+}
+
+func TestAcceptanceDescriptionTemplate(t *testing.T) {
+	if !runAcceptanceTests {
+		t.SkipNow()
+	}
+
+	acceptanceTestEnv, err := newAcceptanceTestEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("add config", acceptanceTestEnv.AddConfig)
+	t.Run("add templated multiteam token role", func(t *testing.T) {
+		resp, err := acceptanceTestEnv.Backend.HandleRequest(acceptanceTestEnv.Context, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/test-multiteam-token",
+			Storage:   acceptanceTestEnv.Storage,
+			Data: map[string]interface{}{
+				"team_id":              acceptanceTestEnv.TeamID,
+				"credential_type":      "team",
+				"description_template": "vault-{{.RoleName}}-{{.UniqueID}}",
+			},
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+	})
+	t.Run("read templated multiteam token cred", func(t *testing.T) {
+		resp, err := acceptanceTestEnv.Backend.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/test-multiteam-token",
+			Storage:   acceptanceTestEnv.Storage,
+		})
+		require.NoError(t, err)
+		require.Regexp(t, regexp.MustCompile(`^vault-test-multiteam-token-`), resp.Data["description"])
+		acceptanceTestEnv.TokenIDs = append(acceptanceTestEnv.TokenIDs, resp.Data["token_id"].(string))
+	})
+	t.Run("cleanup multiteam tokens", acceptanceTestEnv.CleanupMultiTeamTokens)
+}
@@ -0,0 +1,71 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// validateStoreTokenHash checks the store_token_hash setting of a role. A role
+// that only stores the hash of its token cannot hand out a token rotated on a
+// schedule, unless the token is written to sync targets.
+func (r *terraformRoleEntry) validateStoreTokenHash() error {
+	if !r.StoreTokenHash {
+		return nil
+	}
+
+	if !r.isStatic() {
+		return errors.New("store_token_hash is only supported with credential_type = organization or team_legacy")
+	}
+
+	if r.hasRotation() && !r.hasSyncTargets() {
+		return errors.New("store_token_hash with rotation_period or rotation_schedule requires sync_workspace_ids or sync_variable_set_ids, or rotated tokens could not be read")
+	}
+
+	return nil
+}
+
+// setRoleTokenHash sets the salted hash of the token of a role with
+// store_token_hash, before the role is stored without its token. A role read
+// back from storage has no token and keeps its hash.
+func (b *tfBackend) setRoleTokenHash(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry) error {
+	if !roleEntry.StoreTokenHash {
+		roleEntry.TokenHash = ""
+		return nil
+	}
+
+	if roleEntry.Token == "" {
+		return nil
+	}
+
+	hash, err := b.hashToken(ctx, s, roleEntry.Token)
+	if err != nil {
+		return fmt.Errorf("error hashing role token: %w", err)
+	}
+
+	roleEntry.TokenHash = hash
+	return nil
+}
+
+// hashedTokenResponse returns the new token of a role with store_token_hash.
+// The response of the request that created the token is the only place it
+// can be read from.
+func (r *terraformRoleEntry) hashedTokenResponse() *logical.Response {
+	if !r.StoreTokenHash || r.Token == "" {
+		return nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"token":    r.Token,
+			"token_id": r.TokenID,
+		},
+	}
+	resp.AddWarning(fmt.Sprintf("role %q only stores a hash of its token, it will not be returned again", r.Name))
+	return resp
+}
@@ -0,0 +1,134 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreTokenHash(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	tokens := 0
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"GET /api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+		},
+		"POST /api/v2/organizations/acme/authentication-token": func(w http.ResponseWriter, r *http.Request) {
+			tokens++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"data":{"id":"at-org-%d","type":"authentication-tokens","attributes":{"token":"org-token-%d"}}}`, tokens, tokens)
+		},
+	})
+
+	validationCases := map[string]struct {
+		data     map[string]interface{}
+		expected string
+	}{
+		"user role": {
+			data:     map[string]interface{}{"user_id": "user-123", "store_token_hash": true},
+			expected: "only supported with credential_type = organization or team_legacy",
+		},
+		"rotation without sync targets": {
+			data:     map[string]interface{}{"organization": "acme", "store_token_hash": true, "rotation_period": "720h"},
+			expected: "requires sync_workspace_ids or sync_variable_set_ids",
+		},
+	}
+
+	for name, tc := range validationCases {
+		t.Run(name, func(t *testing.T) {
+			tc.data["skip_validation"] = true
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "role/invalid",
+				Storage:   s,
+				Data:      tc.data,
+			})
+			require.NoError(t, err)
+			require.True(t, resp.IsError())
+			require.Contains(t, resp.Error().Error(), tc.expected)
+		})
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/org",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"organization":     "acme",
+			"store_token_hash": true,
+			"skip_validation":  true,
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "org-token-1", resp.Data["token"])
+	require.Equal(t, "at-org-1", resp.Data["token_id"])
+	require.Len(t, resp.Warnings, 1)
+
+	t.Run("storage holds only the hash", func(t *testing.T) {
+		entry, err := s.Get(ctx, "role/org")
+		require.NoError(t, err)
+		require.NotContains(t, string(entry.Value), "org-token-1")
+
+		var stored map[string]interface{}
+		require.NoError(t, json.Unmarshal(entry.Value, &stored))
+		require.NotEmpty(t, stored["token_hash"])
+		require.Equal(t, "at-org-1", stored["token_id"])
+	})
+
+	t.Run("static-creds omits the token", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-creds/org",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.NotContains(t, resp.Data, "token")
+		require.Equal(t, "at-org-1", resp.Data["token_id"])
+		require.Len(t, resp.Warnings, 1)
+	})
+
+	t.Run("rotation returns the new token", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-role/org",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "org-token-2", resp.Data["token"])
+
+		entry, err := s.Get(ctx, "role/org")
+		require.NoError(t, err)
+		require.NotContains(t, string(entry.Value), "org-token-2")
+	})
+
+	t.Run("token is found by hash", func(t *testing.T) {
+		roleEntry, err := b.staticRoleForToken(ctx, s, "org-token-2")
+		require.NoError(t, err)
+		require.NotNil(t, roleEntry)
+		require.Equal(t, "org", roleEntry.Name)
+
+		roleEntry, err = b.staticRoleForToken(ctx, s, "org-token-1")
+		require.NoError(t, err)
+		require.Nil(t, roleEntry)
+	})
+
+	t.Run("export omits the hash", func(t *testing.T) {
+		roleEntry, err := b.getRole(ctx, s, "org")
+		require.NoError(t, err)
+
+		exported, err := roleExportData(roleEntry, true)
+		require.NoError(t, err)
+		require.NotContains(t, exported, "token_hash")
+		require.Equal(t, true, exported["store_token_hash"])
+	})
+}
@@ -0,0 +1,250 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	walTypeRoleToken = "roleToken"
+
+	// walRollbackMinAge gives in-flight role writes time to finish before
+	// their WAL entries are rolled back.
+	walRollbackMinAge = 5 * time.Minute
+)
+
+// walRoleToken records an organization or team_legacy token that is being
+// created for a role, so the token can be cleaned up or the role repaired if
+// the role could not be stored afterwards.
+type walRoleToken struct {
+	RoleName       string `mapstructure:"role_name"`
+	CredentialType string `mapstructure:"credential_type"`
+	Organization   string `mapstructure:"organization"`
+	TeamID         string `mapstructure:"team_id"`
+	TokenID        string `mapstructure:"token_id"`
+}
+
+// credentialType returns the credential type of the role the entry was
+// written for. Entries written before it was recorded are derived from the
+// team.
+func (e *walRoleToken) credentialType() string {
+	switch {
+	case e.CredentialType != "":
+		return e.CredentialType
+	case isTeamToken(e.TeamID):
+		return teamLegacyCredentialType
+	default:
+		return organizationCredentialType
+	}
+}
+
+func (b *tfBackend) walRollback(ctx context.Context, req *logical.Request, kind string, data interface{}) error {
+	switch kind {
+	case walTypeRoleToken:
+		return b.rollbackRoleToken(ctx, req, data)
+	default:
+		return fmt.Errorf("unknown WAL entry type %q", kind)
+	}
+}
+
+// rollbackRoleToken makes sure a role never points at a token that was
+// invalidated by a token creation whose role write did not complete. Since
+// organization and team_legacy tokens are singletons, creating a token
+// upstream always revokes the previous one.
+func (b *tfBackend) rollbackRoleToken(ctx context.Context, req *logical.Request, data interface{}) error {
+	var entry walRoleToken
+	if err := mapstructure.Decode(data, &entry); err != nil {
+		return err
+	}
+
+	// a rotation holding the lock may replace the token in the meantime
+	lock := locksutil.LockForKey(b.roleLocks, entry.RoleName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	roleEntry, err := b.getRole(ctx, req.Storage, entry.RoleName)
+	if err != nil {
+		return err
+	}
+
+	ctx = withLogFields(ctx, "role", entry.RoleName, "token_id", entry.TokenID)
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+
+	currentTokenID, err := readCurrentTokenID(ctx, client, entry.Organization, entry.TeamID)
+	if err != nil {
+		return err
+	}
+
+	if roleEntry == nil {
+		// nobody holds the token, remove it only if it is the one this write
+		// created; any other token belongs to a later, legitimate owner
+		if entry.TokenID == "" || currentTokenID != entry.TokenID {
+			return nil
+		}
+
+		b.Logger().Info("revoking token created for role that was never stored", "role", entry.RoleName)
+		return deleteRoleToken(ctx, client, entry.Organization, entry.TeamID)
+	}
+
+	// the role has since been rewritten for another token owner, which its
+	// own write took care of
+	if roleEntry.CredentialType != entry.credentialType() || roleEntry.Organization != entry.Organization || roleEntry.TeamID != entry.TeamID {
+		b.Logger().Info("role changed since the WAL entry was written, skipping rollback", "role", entry.RoleName)
+		return nil
+	}
+
+	if roleEntry.TokenID != "" && roleEntry.TokenID == currentTokenID {
+		return nil
+	}
+
+	// a new token of a role that only stores its hash could not be read
+	// from anywhere, so leave the replacement to the operator
+	if roleEntry.StoreTokenHash && !roleEntry.hasSyncTargets() {
+		err := fmt.Errorf("role %q holds a revoked token after an incomplete write, rotate it with rotate-role/%s", roleEntry.Name, roleEntry.Name)
+		b.Logger().Error("role token is no longer valid after an incomplete write", "role", roleEntry.Name, "error", err)
+		b.recordError(statusOperationRotation, err)
+		return nil
+	}
+
+	// the stored token was revoked upstream, replace it with a new one
+	b.Logger().Warn("role token is no longer valid after an incomplete write, rotating", "role", entry.RoleName)
+	token, err := b.createToken(ctx, req.Storage, roleEntry, tokenOptions{})
+	if err != nil {
+		return err
+	}
+
+	roleEntry.Token = token.Token
+	roleEntry.TokenID = token.ID
+	roleEntry.LastRotated = time.Now()
+	roleEntry.NextRotation, err = roleEntry.nextRotationAfter(roleEntry.LastRotated)
+	if err != nil {
+		b.Logger().Warn("unable to compute next rotation", "role", roleEntry.Name, "error", err)
+	}
+
+	if err := b.setRoleTokenHash(ctx, req.Storage, roleEntry); err != nil {
+		return err
+	}
+
+	if err := setRole(ctx, req.Storage, roleEntry.Name, roleEntry); err != nil {
+		return err
+	}
+
+	if err := b.syncRoleToken(ctx, req.Storage, roleEntry); err != nil {
+		b.Logger().Error("unable to sync role token", "role", roleEntry.Name, "error", err)
+	}
+
+	return nil
+}
+
+// readCurrentTokenID returns the ID of the active organization or team token,
+// or an empty string if there is none.
+func readCurrentTokenID(ctx context.Context, c *client, organization string, teamID string) (string, error) {
+	if isTeamToken(teamID) {
+		token, err := c.TeamTokens.Read(ctx, teamID)
+		if errors.Is(err, tfe.ErrResourceNotFound) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return token.ID, nil
+	}
+
+	token, err := c.OrganizationTokens.Read(ctx, organization)
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return token.ID, nil
+}
+
+func deleteRoleToken(ctx context.Context, c *client, organization string, teamID string) error {
+	if isTeamToken(teamID) {
+		return c.TeamTokens.Delete(ctx, teamID)
+	}
+	return c.OrganizationTokens.Delete(ctx, organization)
+}
+
+// storeRoleWithToken creates a new organization or team_legacy token for the
+// role and persists the role. A WAL entry guards the window between the two
+// so a failed or interrupted write is repaired by the rollback.
+func (b *tfBackend) storeRoleWithToken(ctx context.Context, s logical.Storage, roleEntry *terraformRoleEntry) error {
+	walEntry := &walRoleToken{
+		RoleName:       roleEntry.Name,
+		CredentialType: roleEntry.CredentialType,
+		Organization:   roleEntry.Organization,
+		TeamID:         roleEntry.TeamID,
+	}
+
+	walID, err := framework.PutWAL(ctx, s, walTypeRoleToken, walEntry)
+	if err != nil {
+		return fmt.Errorf("error writing WAL entry: %w", err)
+	}
+
+	token, err := b.createToken(ctx, s, roleEntry, tokenOptions{})
+	if err != nil {
+		// the previous token is still valid if creation failed
+		if err := framework.DeleteWAL(ctx, s, walID); err != nil {
+			b.Logger().Warn("unable to delete WAL entry", "id", walID, "error", err)
+		}
+		return err
+	}
+
+	// record the token ID so the rollback can tell it apart from a token
+	// created later
+	walEntry.TokenID = token.ID
+	tokenWALID, err := framework.PutWAL(ctx, s, walTypeRoleToken, walEntry)
+	if err != nil {
+		// the first entry stays behind, so the rollback repairs the role
+		return fmt.Errorf("error writing WAL entry: %w", err)
+	}
+	if err := framework.DeleteWAL(ctx, s, walID); err != nil {
+		b.Logger().Warn("unable to delete WAL entry", "id", walID, "error", err)
+	}
+	walID = tokenWALID
+
+	roleEntry.Token = token.Token
+	roleEntry.TokenID = token.ID
+	roleEntry.LastRotated = time.Now()
+	roleEntry.NextRotation, err = roleEntry.nextRotationAfter(roleEntry.LastRotated)
+	if err != nil {
+		b.Logger().Warn("unable to compute next rotation", "role", roleEntry.Name, "error", err)
+	}
+
+	if err := b.setRoleTokenHash(ctx, s, roleEntry); err != nil {
+		return err
+	}
+
+	if err := setRole(ctx, s, roleEntry.Name, roleEntry); err != nil {
+		return err
+	}
+
+	if err := framework.DeleteWAL(ctx, s, walID); err != nil {
+		b.Logger().Warn("unable to delete WAL entry", "id", walID, "error", err)
+	}
+
+	// the token is stored, failing to push it to sync targets is reported on
+	// the role rather than failing the write
+	if err := b.syncRoleToken(ctx, s, roleEntry); err != nil {
+		b.Logger().Error("unable to sync role token", "role", roleEntry.Name, "error", err)
+	}
+
+	return nil
+}
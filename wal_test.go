@@ -0,0 +1,280 @@
+// Copyright IBM Corp. 2020, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALRollbackUnknownKind(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	err := b.walRollback(context.Background(), &logical.Request{Storage: s}, "unknown", nil)
+	require.Error(t, err)
+}
+
+func TestWALRollbackRoleToken(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var created, deleted int
+	currentTokenID := "at-current"
+
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+		},
+		"/api/v2/organizations/acme/authentication-token": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch r.Method {
+			case http.MethodPost:
+				created++
+				currentTokenID = fmt.Sprintf("at-created-%d", created)
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"data":{"id":%q,"type":"authentication-tokens","attributes":{"token":"new-token"}}}`, currentTokenID)
+			case http.MethodDelete:
+				deleted++
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				fmt.Fprintf(w, `{"data":{"id":%q,"type":"authentication-tokens","attributes":{}}}`, currentTokenID)
+			}
+		},
+	})
+
+	rollback := func(t *testing.T, data map[string]interface{}) {
+		t.Helper()
+		data["role_name"] = "org"
+		data["organization"] = "acme"
+		require.NoError(t, b.walRollback(ctx, &logical.Request{Storage: s}, walTypeRoleToken, data))
+	}
+
+	counts := func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return created, deleted
+	}
+
+	t.Run("role never stored and token ID unknown", func(t *testing.T) {
+		rollback(t, map[string]interface{}{})
+		_, deleted := counts()
+		require.Zero(t, deleted)
+	})
+
+	t.Run("role never stored and token since replaced", func(t *testing.T) {
+		rollback(t, map[string]interface{}{"token_id": "at-orphan"})
+		_, deleted := counts()
+		require.Zero(t, deleted)
+	})
+
+	t.Run("role never stored", func(t *testing.T) {
+		rollback(t, map[string]interface{}{"token_id": "at-current"})
+		_, deleted := counts()
+		require.Equal(t, 1, deleted)
+	})
+
+	t.Run("credential type changed", func(t *testing.T) {
+		require.NoError(t, setRole(ctx, s, "org", &terraformRoleEntry{
+			Name:           "org",
+			Organization:   "acme",
+			TeamID:         "team-123",
+			CredentialType: teamCredentialType,
+		}))
+
+		rollback(t, map[string]interface{}{
+			"credential_type": organizationCredentialType,
+			"token_id":        "at-orphan",
+		})
+		created, _ := counts()
+		require.Zero(t, created)
+	})
+
+	t.Run("role token revoked", func(t *testing.T) {
+		require.NoError(t, setRole(ctx, s, "org", &terraformRoleEntry{
+			Name:           "org",
+			Organization:   "acme",
+			CredentialType: organizationCredentialType,
+			Token:          "stale-token",
+			TokenID:        "at-stale",
+		}))
+
+		rollback(t, map[string]interface{}{
+			"credential_type": organizationCredentialType,
+			"token_id":        "at-orphan",
+		})
+		created, _ := counts()
+		require.Equal(t, 1, created)
+
+		roleEntry, err := b.getRole(ctx, s, "org")
+		require.NoError(t, err)
+		require.Equal(t, "at-created-1", roleEntry.TokenID)
+	})
+
+	t.Run("role with store_token_hash is not rotated", func(t *testing.T) {
+		require.NoError(t, setRole(ctx, s, "org", &terraformRoleEntry{
+			Name:           "org",
+			Organization:   "acme",
+			CredentialType: organizationCredentialType,
+			StoreTokenHash: true,
+			TokenHash:      "hash",
+			TokenID:        "at-stale",
+		}))
+
+		rollback(t, map[string]interface{}{
+			"credential_type": organizationCredentialType,
+			"token_id":        "at-orphan",
+		})
+		created, _ := counts()
+		require.Equal(t, 1, created)
+
+		roleEntry, err := b.getRole(ctx, s, "org")
+		require.NoError(t, err)
+		require.Equal(t, "at-stale", roleEntry.TokenID)
+		lastError := b.lastErrors.responseData()[statusOperationRotation].(map[string]interface{})
+		require.Contains(t, lastError["error"], "rotate-role/org")
+	})
+
+	t.Run("waits for a rotation in progress", func(t *testing.T) {
+		require.NoError(t, setRole(ctx, s, "org", &terraformRoleEntry{
+			Name:           "org",
+			Organization:   "acme",
+			CredentialType: organizationCredentialType,
+			Token:          "stale-token",
+			TokenID:        "at-stale",
+		}))
+
+		lock := locksutil.LockForKey(b.roleLocks, "org")
+		lock.Lock()
+
+		done := make(chan error)
+		go func() {
+			done <- b.walRollback(ctx, &logical.Request{Storage: s}, walTypeRoleToken, map[string]interface{}{
+				"role_name":       "org",
+				"organization":    "acme",
+				"credential_type": organizationCredentialType,
+				"token_id":        "at-orphan",
+			})
+		}()
+
+		select {
+		case err := <-done:
+			t.Fatalf("rollback did not wait for the role lock: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// the rotation stores the current token before releasing the lock
+		mu.Lock()
+		tokenID := currentTokenID
+		mu.Unlock()
+		require.NoError(t, setRole(ctx, s, "org", &terraformRoleEntry{
+			Name:           "org",
+			Organization:   "acme",
+			CredentialType: organizationCredentialType,
+			Token:          "rotated-token",
+			TokenID:        tokenID,
+		}))
+		lock.Unlock()
+
+		require.NoError(t, <-done)
+		created, _ := counts()
+		require.Equal(t, 1, created)
+
+		roleEntry, err := b.getRole(ctx, s, "org")
+		require.NoError(t, err)
+		require.Equal(t, "rotated-token", roleEntry.Token)
+	})
+}
+
+func TestStoreRoleWithTokenWALFailure(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	storage := s.(*logical.InmemStorage)
+	newTestServer(t, b, s, map[string]http.HandlerFunc{
+		"/api/v2/organizations/acme": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":{"id":"acme","type":"organizations","attributes":{"name":"acme"}}}`)
+		},
+		"POST /api/v2/organizations/acme/authentication-token": func(w http.ResponseWriter, r *http.Request) {
+			// the WAL entry recording the new token cannot be written
+			storage.FailPut(true)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"data":{"id":"at-new","type":"authentication-tokens","attributes":{"token":"new-token"}}}`)
+		},
+	})
+
+	err := b.storeRoleWithToken(ctx, s, &terraformRoleEntry{
+		Name:           "org",
+		Organization:   "acme",
+		CredentialType: organizationCredentialType,
+	})
+	require.ErrorContains(t, err, "error writing WAL entry")
+
+	// the entry written before the token was created is left for the rollback
+	storage.FailPut(false)
+	walIDs, err := framework.ListWAL(ctx, s)
+	require.NoError(t, err)
+	require.Len(t, walIDs, 1)
+}
+
+// TestAcceptanceWALRollbackRoleToken verifies that a role left pointing at a
+// revoked organization token is repaired by the rollback.
+func TestAcceptanceWALRollbackRoleToken(t *testing.T) {
+	if !runAcceptanceTests {
+		t.SkipNow()
+	}
+
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	organization := checkEnvVars(t, envVarTerraformOrganization)
+	token := checkEnvVars(t, envVarTerraformToken)
+
+	err := testConfigCreate(t, b, s, map[string]interface{}{
+		"token": token,
+	})
+	require.NoError(t, err)
+
+	resp, err := testTokenRoleCreate(t, b, s, roleName, map[string]interface{}{
+		"organization": organization,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	roleEntry, err := b.getRole(ctx, s, roleName)
+	require.NoError(t, err)
+	staleTokenID := roleEntry.TokenID
+
+	// simulate a token created upstream whose role write never completed
+	client, err := b.getClient(ctx, s)
+	require.NoError(t, err)
+	orphan, err := createOrgToken(ctx, client, organization)
+	require.NoError(t, err)
+
+	err = b.walRollback(ctx, &logical.Request{Storage: s}, walTypeRoleToken, map[string]interface{}{
+		"role_name":    roleName,
+		"organization": organization,
+		"token_id":     orphan.ID,
+	})
+	require.NoError(t, err)
+
+	roleEntry, err = b.getRole(ctx, s, roleName)
+	require.NoError(t, err)
+	require.NotEqual(t, staleTokenID, roleEntry.TokenID)
+
+	currentTokenID, err := readCurrentTokenID(ctx, client, organization, "")
+	require.NoError(t, err)
+	require.Equal(t, currentTokenID, roleEntry.TokenID)
+}